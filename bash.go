@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"io"
 	"os"
+	"slices"
 	"strings"
 
 	"github.com/alecthomas/kong"
@@ -14,6 +15,11 @@ type Bash struct {
 	name       string
 	completion []byte
 	Flags      []*kong.Flag // Any global flags that the should Application Node have.
+	Dynamic    bool         // If true only a shim is generated that calls the [CompleteName] command of the application.
+	Validate   bool         // If true Write checks the completion with [Validate] first.
+	// If true the subcommands and flags are shown with their description when there are multiple matches,
+	// subcommands first, then the flags. This needs Bash 4.4 or later.
+	Descriptions bool
 }
 
 func (b *Bash) Out() []byte { return b.completion }
@@ -22,14 +28,22 @@ func (b *Bash) Write(w ...io.Writer) error {
 	if b.completion == nil {
 		return fmt.Errorf("no completion")
 	}
+	if b.Validate {
+		if err := Validate("bash", b.completion); err != nil {
+			return err
+		}
+	}
 	if len(w) > 0 {
 		w[0].Write(b.completion)
 	}
 	return os.WriteFile(b.name+".bash", b.completion, 0644)
 }
 
-func (b *Bash) Completion(k *kong.Node, altname string) {
-	k.Flags = append(k.Flags, b.Flags...)
+func (b *Bash) Completion(k *kong.Node, altname string) error {
+	if err := check(k, b.Flags); err != nil {
+		return err
+	}
+	addFlags(k, b.Flags)
 	format := `# bash completion for %[1]s
 # generated by king (https://github.com/miekg/king) for kong
 
@@ -42,18 +56,44 @@ func (b *Bash) Completion(k *kong.Node, altname string) {
 		k.Name = altname
 	}
 	fmt.Fprintf(&out, format, b.name)
-	b.gen(&out, k)
+	if b.Dynamic {
+		b.shim(&out, k)
+	} else {
+		b.gen(&out, k)
+	}
 	b.completion = []byte(out.String())
+	return nil
 }
 
+// writeFilterFunc writes the filter function, this only returns flags when the current word, cur of the calling
+// function, starts with a dash.
+// Flags already on the command line are left out, unless they are repeatable, given as the third argument.
+// Deprecated flags, given as the second argument, are only returned when they are the only flag matching the
+// current word.
 func (b Bash) writeFilterFunc(buf io.StringWriter) {
 	format := `_%[1]s_filter() {
   COMP_REPLY=()
   local words="$1"
-  local cur=${COMP_WORDS[COMP_CWORD]}
+  local deprecated="$2"
+  local repeatable=" $3 "
   local result=()
 
   if [[ "${cur:0:1}" == "-" ]]; then
+    local given=" ${COMP_WORDS[*]:1:COMP_CWORD-1} "
+    for word in $words; do
+      if [[ "${word:0:1}" == "-" && "$repeatable" != *" $word "* ]]; then
+        [[ "$given" == *" $word "* || "$given" == *" $word="* ]] && continue
+      fi
+      result+=("$word")
+    done
+    words="${result[*]}"
+    if [[ -n "$deprecated" ]]; then
+      local matches=($(compgen -W "$words $deprecated" -- "$cur"))
+      if [[ ${#matches[@]} -eq 1 ]]; then
+        echo "${matches[0]}"
+        return
+      fi
+    fi
     echo "$words"
 
   else
@@ -67,139 +107,407 @@ func (b Bash) writeFilterFunc(buf io.StringWriter) {
 	writeString(buf, fmt.Sprintf(format, b.name))
 }
 
-func (b Bash) compReply(completions []string) string {
-	if len(completions) == 1 && !strings.HasPrefix(completions[0], "$") && !strings.HasPrefix(completions[0], "--") { // action and not empty
-		format := `while read -r; do COMPREPLY+=("$REPLY"); done < <(compgen -A %s -- "$cur")` + "\n"
-		return fmt.Sprintf(format, completions[0])
-	}
-	format := `while read -r; do COMPREPLY+=("$REPLY"); done < <(compgen -W "$(_%s_filter "%s")" -- "$cur")` + "\n"
-	return fmt.Sprintf(format, b.name, strings.Join(completions, " "))
+// writeDescribeFunc writes the function that adds the descriptions to the completions in COMPREPLY, but only when
+// there are multiple. The descriptions are given as a string with a "word<tab>description" line for each word.
+func (b Bash) writeDescribeFunc(buf io.StringWriter) {
+	format := `
+_%[1]s_describe() {
+  (( ${#COMPREPLY[@]} > 1 )) || return
+  local -A help=()
+  local word desc width=0 cmds=() flags=()
+  while IFS=$'\t' read -r word desc; do
+    [[ -n "$word" ]] && help[$word]=$desc
+  done <<< "$1"
+  for word in "${COMPREPLY[@]}"; do
+    (( ${#word} > width )) && width=${#word}
+  done
+  for word in "${COMPREPLY[@]}"; do
+    desc=${help[$word]}
+    if [[ -n "$desc" ]]; then
+      printf -v desc "%%-*s  (%%s)" "$width" "$word" "$desc"
+      (( ${#desc} > ${COLUMNS:-80} - 2 )) && desc="${desc:0:${COLUMNS:-80}-6}...)"
+    else
+      desc=$word
+    fi
+    if [[ "${word:0:1}" == "-" ]]; then
+      flags+=("$desc")
+    else
+      cmds+=("$desc")
+    fi
+  done
+  COMPREPLY=("${cmds[@]}" "${flags[@]}")
+  compopt -o nosort 2>/dev/null
+}
+`
+	writeString(buf, fmt.Sprintf(format, b.name))
 }
 
-func (b Bash) writeFlag(buf io.StringWriter, f *kong.Flag, parents ...string) {
-	if f.Hidden {
-		return
-	}
-	p := ""
-	if len(parents) > 0 {
-		p = parents[0] + " "
+// bashQuote returns s as an ANSI-C quoted bash string: $'...'.
+func bashQuote(s string) string {
+	s = strings.NewReplacer(`\`, `\\`, "'", `\'`, "\n", `\n`, "\t", `\t`).Replace(s)
+	return "$'" + s + "'"
+}
+
+// bashWord returns the literal word w escaped for a double quoted bash string, that is given to compgen -W. As
+// compgen expands the words again, w is escaped twice: once for compgen and once for the double quotes.
+func bashWord(w string) string {
+	s := &strings.Builder{}
+	for _, r := range w {
+		if !safe(r) {
+			s.WriteRune('\\')
+		}
+		s.WriteRune(r)
 	}
-	// 'user add'*'--backup-backend')
-	//   while read -r; do COMPREPLY+=("$REPLY"); done < <(compgen -W "$(_xxx_filter "s3")" -- "$cur")
-	//   ;;
-	completions := []string{}
-	if enums := flagEnums(f); len(enums) > 0 {
-		completions = enums
+	return strings.NewReplacer(`\`, `\\`, `"`, `\"`, "$", `\$`, "`", "\\`").Replace(s.String())
+}
+
+// bashWords returns the literal words escaped with [bashWord], joined with spaces.
+func bashWords(words []string) string {
+	escaped := make([]string, len(words))
+	for i, w := range words {
+		escaped[i] = bashWord(w)
 	}
-	if comptag := completion(f.Value, "bash"); comptag != "" {
-		if f.IsBool() {
-			panic("king: a boolean flag can not have completion")
+	return strings.Join(escaped, " ")
+}
+
+// descriptions returns the "word<tab>description" lines for the subcommands and flags of cmd, quoted with
+// [bashQuote].
+func (b Bash) descriptions(cmd *kong.Node) string {
+	s := &strings.Builder{}
+	for _, c := range cmd.Children {
+		if c == nil || c.Hidden {
+			continue
+		}
+		for _, name := range append([]string{c.Name}, c.Aliases...) {
+			fmt.Fprintf(s, "%s\t%s\n", name, strings.Join(strings.Fields(commandHelp(c)), " "))
 		}
-		completions = []string{comptag}
 	}
-	if envs := flagEnvs(f); len(envs) > 0 {
-		for i := range envs {
-			envs[i] = "$" + envs[i]
+	for _, f := range cmd.Flags {
+		if f.Hidden {
+			continue
+		}
+		for _, name := range flagNames(f) {
+			fmt.Fprintf(s, "%s\t%s\n", name, strings.Join(strings.Fields(flagHelp(f)), " "))
 		}
-		completions = envs
 	}
+	return bashQuote(s.String())
+}
 
-	if len(completions) == 0 { // nothing to complete
-		return
+// cmdReply returns the completion of the subcommands, flags and the positional argument p of cmd, p may be nil.
+// If b.Descriptions is true the descriptions are added.
+func (b Bash) cmdReply(cmd *kong.Node, p *kong.Positional) string {
+	words := bashWords(completions(cmd))
+	action := ""
+	if p != nil {
+		switch comp := completion(p, "bash"); {
+		case strings.HasPrefix(comp, "$"):
+			words = strings.TrimSpace(words + " " + comp)
+		case comp != "":
+			action = comp
+		}
 	}
-	writeString(buf, fmt.Sprintf(`    '%s'*'--%s')`+"\n", strings.TrimSpace(p), f.Name))
-	writeString(buf, "      "+b.compReply(completions))
-	writeString(buf, "      ;;\n")
-	if f.Short != 0 {
-		writeString(buf, fmt.Sprintf(`    '%s'*'-%c')`+"\n", strings.TrimSpace(p), f.Short))
-		writeString(buf, "      "+b.compReply(completions))
-		writeString(buf, "      ;;\n")
+	format := `while read -r; do COMPREPLY+=("$REPLY"); done < <(compgen -W "$(_%s_filter "%s" "%s" "%s")" -- "$cur")`
+	reply := fmt.Sprintf(format, b.name, words, bashWords(deprecatedFlags(cmd)), bashWords(repeatableFlags(cmd)))
+	if action != "" {
+		reply += `; [[ "${cur:0:1}" != "-" ]] && ` + strings.TrimSuffix(b.actionReply(action), "\n")
 	}
+	if b.Descriptions {
+		reply += fmt.Sprintf("; _%s_describe %s", b.name, b.descriptions(cmd))
+	}
+	return reply + "\n"
+}
+
+// actionReply returns the completion of the action, see [toAction].
+func (b Bash) actionReply(action string) string {
+	format := `while read -r; do COMPREPLY+=("$REPLY"); done < <(compgen -A %s -- "$cur")` + "\n"
+	return fmt.Sprintf(format, action)
 }
 
-// writeCommand writes a completion case statement. The optional parent is used to create the correct matching
-// for sub-sub comments
-func (b Bash) writeCommand(buf io.StringWriter, cmd *kong.Node, parents ...string) {
-	p := ""
-	if len(parents) > 0 {
-		p = parents[0] + " "
+// compReply returns the completion of the words, these are already escaped with [bashWord] or are shell commands
+// or environment variables that should be expanded.
+func (b Bash) compReply(words []string) string {
+	format := `while read -r; do COMPREPLY+=("$REPLY"); done < <(compgen -W "$(_%s_filter "%s")" -- "$cur")` + "\n"
+	return fmt.Sprintf(format, b.name, strings.Join(words, " "))
+}
+
+// flagPatterns returns the case patterns that match "$cmd <flag>" for f, where f is a flag of the command with
+// the path: the command itself and all its subcommands, as kong allows flags after subcommands.
+func flagPatterns(path string, f *kong.Flag) string {
+	patterns := []string{}
+	for _, name := range flagNames(f) {
+		patterns = append(patterns, fmt.Sprintf(`'%s %s'|'%s '*' %s'`, path, name, path, name))
 	}
-	if cmd.Type == kong.ApplicationNode {
-		for _, c := range cmd.Children {
-			b.writeCommand(buf, c, p+c.Name)
+	return strings.Join(patterns, "|")
+}
+
+// valueFlags returns the non-hidden flags of cmd and all its children that take a value, the flags of children
+// come before the flags of their parents, so the most specific pattern matches first. A flag that doesn't take a
+// value is returned when it has the name of a value flag of a parent, so the pattern of the parent doesn't
+// match it.
+func valueFlags(cmd *kong.Node) (flags []*kong.Flag, paths []string) {
+	for _, c := range cmd.Children {
+		if c == nil || c.Hidden {
+			continue
 		}
-		return
+		fs, ps := valueFlags(c)
+		flags, paths = append(flags, fs...), append(paths, ps...)
 	}
-	//'group add'*)
-	//  while read -r; do COMPREPLY+=("$REPLY"); done < <(compgen -W "$(_xxx_filter "--gid --auto --man --help")" -- "$cur")
-	//  ;;
-	writeString(buf, fmt.Sprintf(`    '%s'*)`+"\n", strings.TrimSpace(p)))
-	completions := completions(cmd)
-	writeString(buf, "      "+b.compReply(completions))
-	writeString(buf, "      ;;\n")
 	for _, f := range cmd.Flags {
-		b.writeFlag(buf, f, p)
+		if f.Hidden || !takesValue(f) && !shadows(cmd, f) {
+			continue
+		}
+		flags, paths = append(flags, f), append(paths, commandPath(cmd, " "))
+	}
+	return flags, paths
+}
+
+// shadows returns true if f, a flag of cmd, has a name of a flag of one of the parents of cmd that takes a value.
+func shadows(cmd *kong.Node, f *kong.Flag) bool {
+	for parent := cmd.Parent; parent != nil; parent = parent.Parent {
+		for _, pf := range parent.Flags {
+			if pf.Hidden || !takesValue(pf) {
+				continue
+			}
+			for _, name := range flagNames(pf) {
+				if slices.Contains(flagNames(f), name) {
+					return true
+				}
+			}
+		}
+	}
+	return false
+}
+
+// writeWalk writes the case arms that walk the words on the command line: a subcommand, or one of its aliases,
+// switches to that command and a flag that takes a value skips the next word.
+func (b Bash) writeWalk(buf io.StringWriter, cmd *kong.Node) {
+	// 'c group'|'c grp')
+	//   cmd='c group'; pos=0 ;;
+	path := commandPath(cmd, " ")
+	for _, c := range cmd.Children {
+		if c == nil || c.Hidden {
+			continue
+		}
+		patterns := []string{}
+		for _, name := range append([]string{c.Name}, c.Aliases...) {
+			patterns = append(patterns, fmt.Sprintf("'%s %s'", path, name))
+		}
+		writeString(buf, fmt.Sprintf("      %s)\n", strings.Join(patterns, "|")))
+		writeString(buf, fmt.Sprintf("        cmd='%s'; pos=0 ;;\n", commandPath(c, " ")))
 	}
 	for _, c := range cmd.Children {
-		b.writeCommand(buf, c, p+c.Name)
+		if c == nil || c.Hidden {
+			continue
+		}
+		b.writeWalk(buf, c)
+	}
+}
+
+// flagReply returns the completion of the value of the flag f.
+func (b Bash) flagReply(f *kong.Flag) string {
+	completions := []string{}
+	for _, e := range flagEnums(f) {
+		completions = append(completions, bashWord(e))
+	}
+	action := ""
+	switch comptag := completion(f.Value, "bash"); {
+	case strings.HasPrefix(comptag, "$"):
+		completions = []string{comptag}
+	case comptag != "":
+		completions, action = nil, comptag
+	}
+	if envs := flagEnvs(f); len(envs) > 0 {
+		completions, action = nil, ""
+		for _, env := range envs {
+			completions = append(completions, "$"+env)
+		}
+	}
+	if action != "" {
+		return b.actionReply(action)
+	}
+	if len(completions) == 0 { // nothing to complete
+		return ""
+	}
+	if isMap(f.Value) && len(flagEnvs(f)) == 0 && strings.HasPrefix(completions[0], "$(") { // complete the keys as key=
+		format := `compopt -o nospace; while read -r; do COMPREPLY+=("$REPLY"); done < <(compgen -S = -W "%s" -- "$cur")` + "\n"
+		return fmt.Sprintf(format, completions[0])
 	}
+	return b.compReply(completions)
 }
 
-func (b Bash) writeApp(buf io.StringWriter, cmd *kong.Node) {
-	completions := completions(cmd)
-	writeString(buf, "      "+b.compReply(completions))
+// writeCommand writes the case arm that completes the subcommands, flags and positional arguments of cmd and
+// recurses into its children.
+func (b Bash) writeCommand(buf io.StringWriter, cmd *kong.Node) {
+	// 'c group add')
+	//   case $pos in
+	//     0) ... ;;
+	//     *) ... ;;
+	//   esac
+	//   ;;
+	writeString(buf, fmt.Sprintf("    '%s')\n", commandPath(cmd, " ")))
+	writeString(buf, "      case $pos in\n")
+	for i, p := range cmd.Positional {
+		if variadic(p) { // takes all remaining arguments
+			break
+		}
+		writeString(buf, fmt.Sprintf("        %d) %s", i, b.cmdReply(cmd, p)))
+		writeString(buf, "           ;;\n")
+	}
+	writeString(buf, fmt.Sprintf("        *) %s", b.cmdReply(cmd, positional(cmd, len(cmd.Positional)))))
+	writeString(buf, "           ;;\n")
+	writeString(buf, "      esac\n")
 	writeString(buf, "      ;;\n")
+	for _, c := range cmd.Children {
+		if c == nil || c.Hidden {
+			continue
+		}
+		b.writeCommand(buf, c)
+	}
 }
 
+// gen writes a completion function that walks the words on the command line to find the current command, the
+// index of the positional argument and the flag that needs a value. Aliases of commands are resolved and flags
+// are skipped, for flags that take a value the value is skipped as well.
 func (b Bash) gen(buf io.StringWriter, cmd *kong.Node) {
 	b.writeFilterFunc(buf)
-
-	cmdName := funcName(cmd)
-	if b.name != "" {
-		writeString(buf, fmt.Sprintf("\n_%s_completions() {\n", b.name))
-	} else {
-		writeString(buf, fmt.Sprintf("\n_%s_completions() {\n", cmdName))
+	if b.Descriptions {
+		b.writeDescribeFunc(buf)
 	}
-	writeString(buf, `  local cur=${COMP_WORDS[COMP_CWORD]}
-  local compwords=("${COMP_WORDS[@]:1:$COMP_CWORD-1}")
-  local compline="${compwords[*]}"
-
-  case "$compline" in
 
-`)
-	if hasCommands(cmd) {
-		newbuf := &strings.Builder{}
-		b.writeCommand(newbuf, cmd)
-		sorted := sortcmd(newbuf.String())
-		writeString(buf, sorted)
-	}
-	for _, f := range cmd.Flags {
-		b.writeFlag(buf, f)
+	name := b.name
+	if name == "" {
+		name = funcName(cmd)
 	}
-	writeString(buf, "\n"+`    *)`+"\n")
+	root := commandPath(cmd, " ")
+	flags, paths := valueFlags(cmd)
 
-	if hasPositional(cmd) && len(cmd.Positional) > 1 {
-		writeString(buf, "      "+`COMP_CARG=$COMP_CWORD; for i in "${COMP_WORDS[@]}"; do [[ ${i} == -* ]] && ((COMP_CARG = COMP_CARG - 1)); done`+"\n")
-		writeString(buf, "      "+`case $COMP_CARG in`+"\n")
+	writeString(buf, fmt.Sprintf("\n_%s_completions() {\n", name))
+	// Bash splits the words on the characters in COMP_WORDBREAKS, as in "--status", "=", "ok". The words are joined
+	// again when there is no white space between them in COMP_LINE. As bash only replaces the part after the last
+	// '=' or ':', that prefix is removed from the completions.
+	writeString(buf, fmt.Sprintf(`  local line=${COMP_LINE:0:COMP_POINT} words=() rest w i
+  for (( i = 0; i <= COMP_CWORD; i++ )); do
+    w=${COMP_WORDS[i]}
+    rest=${line#"${line%%%%[![:space:]]*}"}
+    if (( i > 1 )) && [[ "$rest" == "$line" ]]; then
+      words[-1]+=$w
+    elif (( i > 0 )); then
+      words+=("$w")
+    fi
+    line=${rest#"$w"}
+  done
+  local cur=${words[-1]}
+  local cmd='%s' pos=0 skip=0 flag="" dashdash=0 prefix=""
+  COMPREPLY=()
 
-		for i, p := range cmd.Positional {
-			writeString(buf, fmt.Sprintf("\n"+`        '%d')`+"\n", i+1))
-			comptag := []string{completion(p, "bash")}
-			writeString(buf, "          "+b.compReply(comptag))
-			writeString(buf, "          return\n          ;;\n")
+  for w in "${words[@]:0:${#words[@]}-1}"; do
+    if (( dashdash )); then
+      (( pos++ ))
+      continue
+    fi
+    if (( skip )); then
+      skip=0
+      continue
+    fi
+    [[ "$w" == "--" ]] && dashdash=1 && continue
+    case "$cmd $w" in
+`, root))
+	b.writeWalk(buf, cmd)
+	for i, f := range flags {
+		writeString(buf, fmt.Sprintf("      %s)\n", flagPatterns(paths[i], f)))
+		if takesValue(f) {
+			writeString(buf, "        skip=1; flag=$w ;;\n")
+		} else {
+			writeString(buf, "        ;;\n")
 		}
-
-		writeString(buf, "\n      "+`esac`+"\n\n")
 	}
+	writeString(buf, `      *' -'*)
+        ;;
+      *)
+        (( pos++ )) ;;
+    esac
+  done
+  if (( ! skip && ! dashdash )) && [[ "$cur" == -*=* ]]; then
+    skip=1; flag=${cur%%=*}; cur=${cur#*=}
+  fi
+  rest=${COMP_WORDS[COMP_CWORD]}
+  [[ -z "${rest//[=:]/}" ]] && rest=""
+  [[ "$cur" == *"$rest" ]] && prefix=${cur%"$rest"}
 
-	b.writeApp(buf, cmd)
+  if (( skip )); then
+    case "$cmd $flag" in
+`)
+	for i, f := range flags {
+		if !takesValue(f) {
+			continue
+		}
+		if reply := b.flagReply(f); reply != "" {
+			writeString(buf, fmt.Sprintf("      %s)\n", flagPatterns(paths[i], f)))
+			writeString(buf, "        "+reply)
+			writeString(buf, "        ;;\n")
+		}
+	}
+	writeString(buf, `    esac
+    [[ -n "$prefix" ]] && COMPREPLY=("${COMPREPLY[@]#"$prefix"}")
+    return
+  fi
 
-	writeString(buf, `esac
+  case "$cmd" in
+`)
+	b.writeCommand(buf, cmd)
+	writeString(buf, "  esac\n")
+	writeString(buf, `  [[ -n "$prefix" ]] && COMPREPLY=("${COMPREPLY[@]#"$prefix"}")`+"\n")
+	writeString(buf, "} &&\n")
+	writeString(buf, fmt.Sprintf("complete -F _%[1]s_completions %[1]s\n", name))
+}
 
-} &&`)
-	if b.name != "" {
-		writeString(buf, fmt.Sprintf("\ncomplete -F _%[1]s_completions %[1]s\n", b.name))
-	} else {
-		writeString(buf, fmt.Sprintf("\ncomplete -F _%[1]s_completions %[1]s\n", cmdName))
+// shim writes a completion function that calls the application to get the completions.
+func (b Bash) shim(buf io.StringWriter, cmd *kong.Node) {
+	name := strings.ReplaceAll(b.name, "-", "_")
+	describe := ""
+	if b.Descriptions {
+		d := b
+		d.name = name
+		d.writeDescribeFunc(buf)
+		writeString(buf, "\n")
+		describe = fmt.Sprintf("  _%s_describe \"$descs\"\n", name)
 	}
+	// Bash splits the words on the characters in COMP_WORDBREAKS, as in "--status", "=", "ok". The words are joined
+	// again when there is no white space between them in COMP_LINE, so the application sees the words as typed.
+	// As bash only replaces the part after the last '=' or ':', that prefix is removed from the completions.
+	format := `_%[1]s_completions() {
+  local line=${COMP_LINE:0:COMP_POINT} words=() rest w i
+  for (( i = 0; i <= COMP_CWORD; i++ )); do
+    w=${COMP_WORDS[i]}
+    rest=${line#"${line%%%%[![:space:]]*}"}
+    if (( i > 1 )) && [[ "$rest" == "$line" ]]; then
+      words[-1]+=$w
+    elif (( i > 0 )); then
+      words+=("$w")
+    fi
+    line=${rest#"$w"}
+  done
+  local cur=${words[-1]}
+  rest=$cur
+  [[ ${COMP_WORDS[COMP_CWORD]} != "$cur" ]] && rest=${cur##*[=:]}
+  local prefix=${cur:0:${#cur}-${#rest}}
+  local action="" descs=""
+  COMPREPLY=()
+
+  while IFS= read -r line; do
+    case "$line" in
+      :*) action=${line:1} ;;
+      *) COMPREPLY+=("${line%%%%$'\t'*}"); descs+=$line$'\n' ;;
+    esac
+  done < <(%[2]s %[3]s -- "${words[@]}" 2>/dev/null)
+  [[ ${#COMPREPLY[@]} -eq 1 && ${COMPREPLY[0]} == *= ]] && compopt -o nospace
+  [[ -n "$prefix" ]] && COMPREPLY=("${COMPREPLY[@]#"$prefix"}")
+
+  if [[ -n "$action" ]]; then
+    while read -r; do COMPREPLY+=("$REPLY"); done < <(compgen -A "$action" -- "$rest")
+  fi
+%[4]s} &&
+complete -F _%[1]s_completions %[2]s
+`
+	writeString(buf, fmt.Sprintf(format, name, b.name, CompleteName, describe))
 }
@@ -1,19 +1,273 @@
 package king
 
 import (
+	"os/exec"
+	"reflect"
+	"strings"
 	"testing"
 
 	"github.com/alecthomas/kong"
+	"github.com/miekg/king/internal/comptest"
 )
 
-func compbTest(t *testing.T, completionfile, exe string) {
-	// This can be tested in prolly way:
-	//COMP_REPLY=() COMPREPLY="" COMP_WORDS=() COMP_CWORD=1  _myexe_completions ; echo "${COMPREPLY[*]}"
+// compbTest returns the completions bash gives for line with the completion script, the completions are joined
+// with a space.
+func compbTest(t *testing.T, script []byte, line string) string {
+	t.Helper()
+	if _, err := exec.LookPath("bash"); err != nil {
+		t.Skip("bash not found")
+	}
+	completions, err := comptest.Bash(script, line)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return strings.Join(completions, " ")
 }
 
 func TestBash(t *testing.T) {
 	parser := kong.Must(&T{})
 	b := &Bash{}
-	b.Completion(parser.Model.Node, "myexe")
-	b.Write()
+	if err := b.Completion(parser.Model.Node, "myexe"); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		line   string
+		expect string
+	}{
+		// subcommands
+		{"myexe ", "do d more again even-more more"},
+		{"myexe ev", "even-more"},
+		{"myexe even-more ", "do-even-more what-even-more"},
+		{"myexe even-more w", "what-even-more"},
+		// aliases
+		{"myexe d ", "a b c"},
+		{"myexe again ", "a b c"},
+		// flags
+		{"myexe -", "--help -h"},
+		{"myexe do --e", "--enddate"},
+		{"myexe --help even-more ", "do-even-more what-even-more"},
+		{"myexe do --status ok --s", "--super-string"},
+		// flag values
+		{"myexe do --status ", "ok setup dst archive rm"},
+		{"myexe do -s s", "setup"},
+		{"myexe do --status=", "ok setup dst archive rm"},
+		{"myexe do --status=a", "archive"},
+		{"myexe do --super-string ", "bla bloep"},
+		// actions
+		{"myexe do --file go.m", "go.mod"},
+		{"myexe even-more do-even-more --string go.s", "go.sum"},
+		// positional arguments
+		{"myexe do --status ok ", "a b c"},
+		{"myexe do a ", ""},
+		{"myexe do -- ", "a b c"},
+		{"myexe even-more do-even-more ", ""},
+	}
+	for _, tc := range tests {
+		if got := compbTest(t, b.Out(), tc.line); got != tc.expect {
+			t.Errorf("for %q, expected %q, got %q", tc.line, tc.expect, got)
+		}
+	}
+}
+
+func TestBashDescriptions(t *testing.T) {
+	parser := kong.Must(&T{})
+	b := &Bash{Descriptions: true}
+	if err := b.Completion(parser.Model.Node, "myexe"); err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		line   string
+		expect string
+	}{
+		{"myexe ", "do         (do it) d          (do it) more       (do it another time) again      (do it again) even-more  (do it another time) more       (do it another time)"},
+		{"myexe -", "--help  (Show context-sensitive help.) -h      (Show context-sensitive help.)"},
+		{"myexe ev", "even-more"},
+	}
+	for _, tc := range tests {
+		if got := compbTest(t, b.Out(), tc.line); got != tc.expect {
+			t.Errorf("for %q, expected %q, got %q", tc.line, tc.expect, got)
+		}
+	}
+}
+
+func TestBashHostile(t *testing.T) {
+	parser := kong.Must(&H{})
+	b := &Bash{}
+	if err := b.Completion(parser.Model.Node, "h"); err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		line   string
+		expect string
+	}{
+		{"h --quote i", "it's"},
+		{"h --quote a", `a"b a[1] a:b a b`},
+		{"h --quote $H", "$HOME"},
+		{"h --quote `", "`date`"},
+		{"h --quote (", "(x)"},
+		{"h --quote a:", "b"},
+		{"h --quote=a:", "b"},
+		{"h --quote=a:b", "b"},
+	}
+	for _, tc := range tests {
+		if got := compbTest(t, b.Out(), tc.line); got != tc.expect {
+			t.Errorf("for %q, expected %q, got %q", tc.line, tc.expect, got)
+		}
+	}
+}
+
+func TestBashShim(t *testing.T) {
+	parser := kong.Must(&G{})
+	b := &Bash{Dynamic: true}
+	if err := b.Completion(parser.Model.Node, "g"); err != nil {
+		t.Fatal(err)
+	}
+	script := append([]byte(helper(t)), b.Out()...)
+	tests := []struct {
+		line   string
+		expect string
+	}{
+		{"g vol", "volume vol"},
+		{"g volume list --status ", "ok setup rm"},
+		{"g volume list --status=", "ok setup rm"},
+		{"g volume list --status=s", "setup"},
+		{"g volume list -s ok --ta", "--tag"},
+		{"g volume list --label=", "team= owner="},
+		{"g --config=go.m", "go.mod"},
+		{"g server w", "web:data"},
+		{"g server web:", "data"},
+		{"g server web:d", "data"},
+		{"g server --region=e", "eu"},
+	}
+	for _, tc := range tests {
+		if got := compbTest(t, script, tc.line); got != tc.expect {
+			t.Errorf("for %q, expected %q, got %q", tc.line, tc.expect, got)
+		}
+	}
+}
+
+func TestBashWordBreaks(t *testing.T) {
+	parser := kong.Must(&G{})
+	b := &Bash{}
+	if err := b.Completion(parser.Model.Node, "g"); err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		line   string
+		expect string
+	}{
+		{"g volume list --status=", "ok setup rm"},
+		{"g volume list --status=s", "setup"},
+		{"g volume list --status=ok --ta", "--tag"},
+		{"g volume list --status=ok ", "vol1 vol2"},
+		{"g volume create --dir=testd", "testdata"},
+		{"g server w", "web:data"},
+		{"g server web:", "data"},
+		{"g server web:d", "data"},
+		{"g server --region=eu web:", "data"},
+	}
+	for _, tc := range tests {
+		if got := compbTest(t, b.Out(), tc.line); got != tc.expect {
+			t.Errorf("for %q, expected %q, got %q", tc.line, tc.expect, got)
+		}
+	}
+}
+
+func TestBashAliases(t *testing.T) {
+	parser := kong.Must(&G{})
+	b := &Bash{}
+	if err := b.Completion(parser.Model.Node, "g"); err != nil {
+		t.Fatal(err)
+	}
+	for _, line := range []string{"g ", "g volume "} {
+		cands, _ := Complete(parser.Model.Node, comptest.Words(line)[1:])
+		words := []string{}
+		for _, c := range cands {
+			words = append(words, c.Value)
+		}
+		if got, expect := compbTest(t, b.Out(), line), strings.Join(words, " "); got != expect {
+			t.Errorf("for %q, expected %q as with Complete, got %q", line, expect, got)
+		}
+	}
+}
+
+func TestBashShadowedFlag(t *testing.T) {
+	parser := kong.Must(&G{})
+	// kong doesn't allow this, but a flag can be added to the node after parsing.
+	server := parser.Model.Node.Children[1]
+	server.Flags = append(server.Flags, &kong.Flag{Value: &kong.Value{Name: "config", Help: "Show the configuration.", Tag: &kong.Tag{}, Target: reflect.ValueOf(new(bool)).Elem()}})
+	b := &Bash{}
+	if err := b.Completion(parser.Model.Node, "g"); err != nil {
+		t.Fatal(err)
+	}
+	if got := compbTest(t, b.Out(), "g server --config "); got != "web:data db:logs" {
+		t.Errorf("expected %q, got %q", "web:data db:logs", got)
+	}
+	if got := compbTest(t, b.Out(), "g volume list --config "); got != "" {
+		t.Errorf("expected the value of --config, got %q", got)
+	}
+}
+
+func TestBashDeprecated(t *testing.T) {
+	parser := kong.Must(&D{})
+	b := &Bash{}
+	if err := b.Completion(parser.Model.Node, "d"); err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		line   string
+		expect string
+	}{
+		{"d --", "--help --format --version"},
+		{"d --ve", "--version"},
+		{"d --verb", "--verbose"},
+		{"d --j", "--json"},
+	}
+	for _, tc := range tests {
+		if got := compbTest(t, b.Out(), tc.line); got != tc.expect {
+			t.Errorf("for %q, expected %q, got %q", tc.line, tc.expect, got)
+		}
+	}
+}
+
+func TestBashRepeatable(t *testing.T) {
+	parser := kong.Must(&R{})
+	b := &Bash{}
+	if err := b.Completion(parser.Model.Node, "r"); err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		line   string
+		expect string
+	}{
+		{"r --status ok -v --", "--help --verbose --tag --label"},
+		{"r --label t", "team="},
+	}
+	for _, tc := range tests {
+		if got := compbTest(t, b.Out(), tc.line); got != tc.expect {
+			t.Errorf("for %q, expected %q, got %q", tc.line, tc.expect, got)
+		}
+	}
+}
+
+func TestBashVariadic(t *testing.T) {
+	parser := kong.Must(&V{})
+	b := &Bash{}
+	if err := b.Completion(parser.Model.Node, "v"); err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		line   string
+		expect string
+	}{
+		{"v rm ", ""},
+		{"v rm s1 ", "a b"},
+		{"v rm s1 a b ", "a b"},
+	}
+	for _, tc := range tests {
+		if got := compbTest(t, b.Out(), tc.line); got != tc.expect {
+			t.Errorf("for %q, expected %q, got %q", tc.line, tc.expect, got)
+		}
+	}
 }
@@ -6,6 +6,9 @@ package king
 import (
 	"fmt"
 	"io"
+	"regexp"
+	"slices"
+	"strconv"
 	"strings"
 
 	"github.com/alecthomas/kong"
@@ -14,8 +17,8 @@ import (
 // The Completer interface must be implemented by every shell completer. It mainly serves for documentation.
 type Completer interface {
 	// Completion generates the completion for a shell starting with k. The altname - if not empty - takes
-	// precedence over k.Name.
-	Completion(k *kong.Node, altname string)
+	// precedence over k.Name. If the node tree has problems an *[Error] is returned.
+	Completion(k *kong.Node, altname string) error
 	// Out returns the generated shell completion script.
 	Out() []byte
 	// Write writes the generated shell completion script to the appropiate file, for Zsh this is _exename and
@@ -27,7 +30,9 @@ type Completer interface {
 var (
 	_ Completer = (*Zsh)(nil)
 	_ Completer = (*Bash)(nil)
-	_ Completer = (*Bash)(nil)
+	_ Completer = (*Fish)(nil)
+	_ Completer = (*PowerShell)(nil)
+	_ Completer = (*Nushell)(nil)
 )
 
 // commandName returns the name of the command node, it takes name from the cmd tag, if that is empty the
@@ -55,6 +60,14 @@ func nodePath(n *kong.Node, path []string) *kong.Node {
 	return nil
 }
 
+// commandPath returns the names of the nodes from the root to n, joined with sep.
+func commandPath(n *kong.Node, sep string) string {
+	if n.Parent == nil {
+		return n.Name
+	}
+	return commandPath(n.Parent, sep) + sep + n.Name
+}
+
 // funcName returns the full path of the kong node for use as a function name. Any alias is ignored.
 func funcName(n *kong.Node) (out string) {
 	root := n
@@ -89,10 +102,135 @@ func hasCommands(cmd *kong.Node) bool {
 	return false
 }
 
+// Example is an example of how to use a command, it is shown in the Examples section of the manual page.
+type Example struct {
+	Command     string // The command line, this may span multiple lines.
+	Explanation string
+}
+
+// examples returns the examples from the examples tags of the node. Each tag holds a single example, where the
+// explanation follows the command after " # ": `examples:"c volume list # List all volumes."`.
+func examples(n *kong.Node) []Example {
+	if n.Tag == nil {
+		return nil
+	}
+	exs := []Example{}
+	for _, e := range n.Tag.GetAll("examples") {
+		if i := strings.LastIndex(e, " # "); i >= 0 {
+			exs = append(exs, Example{strings.TrimSpace(e[:i]), strings.TrimSpace(e[i+3:])})
+			continue
+		}
+		exs = append(exs, Example{Command: strings.TrimSpace(e)})
+	}
+	return exs
+}
+
+// ExitCode documents an exit code of a command, it is shown in the Exit Status section of the manual page.
+type ExitCode struct {
+	Code    int
+	Meaning string
+}
+
+// exitCodes returns the exit codes from the exitcodes tag of the node, as in `exitcodes:"0=ok,2=usage"`. Any
+// items that can't be parsed are returned in bad.
+func exitCodes(n *kong.Node) (codes []ExitCode, bad []string) {
+	if n.Tag == nil || n.Tag.Get("exitcodes") == "" {
+		return nil, nil
+	}
+	for _, item := range strings.Split(n.Tag.Get("exitcodes"), ",") {
+		code, meaning, ok := strings.Cut(item, "=")
+		i, err := strconv.Atoi(strings.TrimSpace(code))
+		if !ok || err != nil || i < 0 || i > 255 {
+			bad = append(bad, item)
+			continue
+		}
+		codes = append(codes, ExitCode{i, strings.TrimSpace(meaning)})
+	}
+	return codes, bad
+}
+
+// File documents a file used by a command, it is shown in the Files section of the manual page.
+type File struct {
+	Path        string
+	Description string
+}
+
+// files returns the files from the files tag of the node, as in `files:"/etc/c.conf=The configuration file."`.
+// Multiple files are separated by a comma.
+func files(n *kong.Node) []File {
+	if n.Tag == nil || n.Tag.Get("files") == "" {
+		return nil
+	}
+	fs := []File{}
+	for _, item := range strings.Split(n.Tag.Get("files"), ",") {
+		path, desc, _ := strings.Cut(item, "=")
+		fs = append(fs, File{strings.TrimSpace(path), strings.TrimSpace(desc)})
+	}
+	return fs
+}
+
+// seeAlso returns the references from the seealso tag of the node, as in `seealso:"ls(1),mount(8)"`.
+func seeAlso(n *kong.Node) []string {
+	if n.Tag == nil || n.Tag.Get("seealso") == "" {
+		return nil
+	}
+	refs := []string{}
+	for _, ref := range strings.Split(n.Tag.Get("seealso"), ",") {
+		refs = append(refs, strings.TrimSpace(ref))
+	}
+	return refs
+}
+
+// commandHelp returns the help of the command as shown in the completions, if the command has examples the first
+// one is added.
+func commandHelp(n *kong.Node) string {
+	exs := examples(n)
+	if len(exs) == 0 {
+		return plain(n.Help)
+	}
+	return fmt.Sprintf("%s (e.g. %s)", strings.TrimSuffix(plain(n.Help), "."), strings.Join(strings.Fields(exs[0].Command), " "))
+}
+
+var (
+	mdEmphasis = regexp.MustCompile(`\*{1,3}([^*\s](?:[^*]*[^*\s])?)\*{1,3}`)
+	mdCode     = regexp.MustCompile("`([^`]+)`")
+	mdLink     = regexp.MustCompile(`\[([^\]]+)\]\([^)]*\)`)
+)
+
+// plain returns the help text s without the markdown markup, as used in the manual page, and on a single line.
+// This is used for the descriptions in the completions.
+func plain(s string) string {
+	s = mdEmphasis.ReplaceAllString(s, "$1")
+	s = mdCode.ReplaceAllString(s, "$1")
+	s = mdLink.ReplaceAllString(s, "$1")
+	return strings.Join(strings.Fields(s), " ")
+}
+
+// safe returns true if r doesn't need to be escaped in a shell word.
+func safe(r rune) bool {
+	return r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || strings.ContainsRune("-_.,:/=+@%", r)
+}
+
 // hasPositional returns true if there are positional arguments.
 func hasPositional(cmd *kong.Node) bool { return len(cmd.Positional) > 0 }
 
-// completions returns all completions that this kong.Node has.
+// variadic returns true if the positional argument takes all remaining arguments, as in []string with arg:"".
+func variadic(p *kong.Positional) bool { return p.Target.IsValid() && p.IsSlice() }
+
+// positional returns the positional argument of cmd at index i, if the last positional argument is variadic it
+// is returned for all indices beyond it. If there is no such argument nil is returned.
+func positional(cmd *kong.Node, i int) *kong.Positional {
+	if i < len(cmd.Positional) {
+		return cmd.Positional[i]
+	}
+	if n := len(cmd.Positional); n > 0 && variadic(cmd.Positional[n-1]) {
+		return cmd.Positional[n-1]
+	}
+	return nil
+}
+
+// completions returns the subcommands, with their aliases, and flags of this kong.Node, deprecated flags are
+// left out, see [deprecatedFlags].
 func completions(cmd *kong.Node) []string {
 	completions := []string{}
 	for _, c := range cmd.Children {
@@ -100,9 +238,10 @@ func completions(cmd *kong.Node) []string {
 			continue
 		}
 		completions = append(completions, c.Name)
+		completions = append(completions, c.Aliases...)
 	}
 	for _, f := range cmd.Flags {
-		if f.Hidden {
+		if _, ok := deprecated(f); f.Hidden || ok {
 			continue
 		}
 		completions = append(completions, "--"+f.Name)
@@ -113,16 +252,43 @@ func completions(cmd *kong.Node) []string {
 			completions = append(completions, "--no-"+f.Name)
 		}
 	}
-	for _, p := range cmd.Positional {
-		completions = append(completions, completion(p, "bash"))
-	}
 	return completions
 }
 
+// deprecatedFlags returns the flags of cmd that are deprecated.
+func deprecatedFlags(cmd *kong.Node) []string {
+	flags := []string{}
+	for _, f := range cmd.Flags {
+		if _, ok := deprecated(f); f.Hidden || !ok {
+			continue
+		}
+		flags = append(flags, "--"+f.Name)
+		if f.Short != 0 {
+			flags = append(flags, "-"+fmt.Sprintf("%c", f.Short))
+		}
+		if f.Tag.Negatable != "" {
+			flags = append(flags, "--no-"+f.Name)
+		}
+	}
+	return flags
+}
+
+// repeatableFlags returns the flags of cmd that can be given multiple times, see [repeatable].
+func repeatableFlags(cmd *kong.Node) []string {
+	flags := []string{}
+	for _, f := range cmd.Flags {
+		if f.Hidden || !repeatable(f.Value) {
+			continue
+		}
+		flags = append(flags, flagNames(f)...)
+	}
+	return flags
+}
+
 // completion returns the completion for the shell for the kong.Value.
 func completion(cmd *kong.Value, shell string) string {
 	comp := cmd.Tag.Get("completion")
-	if comp == "" {
+	if comp == "" || strings.HasPrefix(comp, "@") { // predictors are only used in dynamic completion
 		return ""
 	}
 	if strings.HasPrefix(comp, "<") && strings.HasSuffix(comp, ">") {
@@ -135,6 +301,15 @@ func completion(cmd *kong.Value, shell string) string {
 // writeString writes a string into a buffer, and checks if the error is not nil.
 func writeString(b io.StringWriter, s string) { b.WriteString(s) }
 
+// addFlags adds the flags to k, flags that k already has are not added again.
+func addFlags(k *kong.Node, flags []*kong.Flag) {
+	for _, f := range flags {
+		if !slices.Contains(k.Flags, f) {
+			k.Flags = append(k.Flags, f)
+		}
+	}
+}
+
 func flagEnums(flag *kong.Flag) []string {
 	values := make([]string, 0)
 	for _, enum := range flag.EnumSlice() {
@@ -145,6 +320,81 @@ func flagEnums(flag *kong.Flag) []string {
 	return values
 }
 
+// deprecated returns the deprecation message of the flag and true if the flag has a deprecated tag. The message
+// is the value of the tag, as in `deprecated:"use --foo"`, and may be empty.
+func deprecated(flag *kong.Flag) (string, bool) {
+	if flag.Tag == nil || !flag.Tag.Has("deprecated") {
+		return "", false
+	}
+	return flag.Tag.Get("deprecated"), true
+}
+
+// flagHelp returns the help of the flag as shown in the completions, for deprecated flags this is prefixed with
+// "(deprecated) " or "(deprecated: <message>) ".
+func flagHelp(flag *kong.Flag) string {
+	msg, ok := deprecated(flag)
+	switch {
+	case !ok:
+		return plain(flag.Help)
+	case msg == "":
+		return "(deprecated) " + plain(flag.Help)
+	}
+	return fmt.Sprintf("(deprecated: %s) %s", msg, plain(flag.Help))
+}
+
+// repeatable returns true if the value can be given multiple times, this is true for counters, slices and maps.
+func repeatable(v *kong.Value) bool { return v.IsCounter() || (v.Target.IsValid() && v.IsCumulative()) }
+
+// isMap returns true if the value is a map, these are given as key=value.
+func isMap(v *kong.Value) bool { return v.Target.IsValid() && v.IsMap() }
+
+// takesValue returns true if the flag is followed by a value.
+func takesValue(flag *kong.Flag) bool { return !flag.IsBool() && !flag.IsCounter() }
+
+// exclusive returns the flags that can't be given together with flag: the flag itself, unless it is
+// repeatable, and the flags that share a xor group with it.
+func exclusive(flag *kong.Flag, flags []*kong.Flag) []*kong.Flag {
+	excl := []*kong.Flag{}
+	if !repeatable(flag.Value) {
+		excl = append(excl, flag)
+	}
+	for _, f := range flags {
+		if f == flag || f.Hidden {
+			continue
+		}
+		for _, xor := range f.Xor {
+			if slices.Contains(flag.Xor, xor) {
+				excl = append(excl, f)
+				break
+			}
+		}
+	}
+	return excl
+}
+
+// flagNames returns all names the flag can be given with on the command line: "--flag", "-f" and "--no-flag".
+func flagNames(flag *kong.Flag) []string {
+	names := []string{"--" + flag.Name}
+	if flag.Short != 0 {
+		names = append(names, fmt.Sprintf("-%c", flag.Short))
+	}
+	if neg := negation(flag); neg != "" {
+		names = append(names, neg)
+	}
+	return names
+}
+
+// negation returns the negated flag name, as in "--no-flag", or the empty string if the flag isn't negatable.
+func negation(flag *kong.Flag) string {
+	switch flag.Tag.Negatable {
+	case "":
+		return ""
+	case "_":
+		return "--no-" + flag.Name
+	}
+	return "--" + flag.Tag.Negatable
+}
+
 func flagEnvs(flag *kong.Flag) []string {
 	values := make([]string, 0)
 	for _, env := range flag.Envs {
@@ -163,7 +413,11 @@ func toAction(action, shell string) string {
 	case "bash":
 		return action
 	case "fish":
-		return ""
+		return fishActions[action]
+	case "powershell":
+		return powershellActions[action]
+	case "nushell":
+		return nushellActions[action]
 	}
 	return ""
 }
@@ -175,3 +429,27 @@ var zshActions = map[string]string{
 	"user":      "_users",
 	"export":    "_parameters",
 }
+
+var fishActions = map[string]string{
+	"file":      "(__fish_complete_path (commandline -ct))",
+	"directory": "(__fish_complete_directories (commandline -ct))",
+	"group":     "(__fish_complete_groups)",
+	"user":      "(__fish_complete_users)",
+	"export":    "(set -n)",
+}
+
+var powershellActions = map[string]string{
+	"file":      "_king_paths $wordToComplete",
+	"directory": "_king_paths $wordToComplete -directory",
+	"group":     "Get-Content /etc/group -ErrorAction SilentlyContinue | ForEach-Object { $_.Split(':')[0] }",
+	"user":      "Get-Content /etc/passwd -ErrorAction SilentlyContinue | ForEach-Object { $_.Split(':')[0] }",
+	"export":    "Get-ChildItem Env: | ForEach-Object Name",
+}
+
+var nushellActions = map[string]string{
+	"file":      "path",
+	"directory": "directory",
+	"group":     "open /etc/group | lines | split column ':' | get column1",
+	"user":      "open /etc/passwd | lines | split column ':' | get column1",
+	"export":    "$env | columns",
+}
@@ -1,17 +1,14 @@
 package king
 
 import (
+	"bytes"
+	"slices"
 	"testing"
 	"time"
 
 	"github.com/alecthomas/kong"
 )
 
-type CompTest struct {
-	Compfile string
-	Comptest string
-}
-
 type T struct {
 	Do   T1 `cmd:"" aliases:"d" help:"do it"`
 	More T1 `cmd:"MorethenEver" aliases:"again" help:"do it again" description:"T1 whay more do you want."`
@@ -47,18 +44,88 @@ type (
 	T4 struct{}
 )
 
+type X struct {
+	JSON   bool   `help:"output json." xor:"format"`
+	YAML   bool   `help:"output yaml." xor:"format" negatable:""`
+	Status string `help:"set the status." short:"s"`
+}
+
+type R struct {
+	Verbose int               `help:"be verbose." type:"counter" short:"v"`
+	Tag     []string          `help:"add a tag."`
+	Label   map[string]string `help:"set a label." completion:"echo env team"`
+	Status  string            `help:"set the status." enum:"ok,rm" default:"ok"`
+}
+
+type V struct {
+	Rm struct {
+		Server  string   `arg:"" optional:"" help:"the server."`
+		Volumes []string `arg:"" optional:"" help:"the volumes." completion:"echo a b"`
+	} `cmd:"" help:"remove volumes."`
+}
+
 type T5 struct {
 	Bool *bool `help:"hello" completion:"blaa"`
+	Sub  struct {
+		File string `help:"a file" completion:"<fiel>"`
+	} `cmd:"" help:"a command" exitcodes:"two=usage"`
+}
+
+func TestBoolCompletionError(t *testing.T) {
+	parser := kong.Must(&T5{}, kong.Name("t5"))
+	flags := len(parser.Model.Node.Flags)
+	for _, c := range []Completer{&Zsh{}, &Bash{}, &Fish{Flags: []*kong.Flag{manf}}, &PowerShell{}, &Nushell{}} {
+		err := c.Completion(parser.Model.Node, "other")
+		kerr, ok := err.(*Error)
+		if !ok {
+			t.Fatalf("expected *Error, got %v", err)
+		}
+		fields := []string{}
+		for _, p := range kerr.Problems {
+			fields = append(fields, p.Path+" "+p.Field+" "+p.Tag)
+		}
+		expect := []string{`t5 --bool completion:"blaa"`, `t5 sub  exitcodes:"two=usage"`, `t5 sub --file completion:"<fiel>"`}
+		if !slices.Equal(fields, expect) {
+			t.Errorf("expected problems %q, got %q", expect, fields)
+		}
+	}
+	if node := parser.Model.Node; node.Name != "t5" || len(node.Flags) != flags {
+		t.Errorf("expected the node to be left alone, got %q with %d flags", node.Name, len(node.Flags))
+	}
+}
+
+func TestCompletionTwice(t *testing.T) {
+	parser := kong.Must(&G{})
+	b := &Bash{Flags: []*kong.Flag{manf}}
+	for range 2 {
+		if err := b.Completion(parser.Model.Node, "g"); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if n := bytes.Count(b.Out(), []byte("'g --man'|")); n != 1 {
+		t.Errorf("expected --man to be walked once, got %d times", n)
+	}
+}
+
+type D struct {
+	Format  string `help:"output format." enum:"json,text" default:"text"`
+	Json    bool   `help:"output json." deprecated:"use --format=json"`
+	Verbose bool   `help:"be verbose." deprecated:""`
+	Version bool   `help:"show version."`
 }
 
-func TestBoolCompletionPanic(t *testing.T) {
-	defer func() {
-		// without this recover it would panic and thus fail the test
-		if r := recover(); r != nil {
-			t.Log("Recovered", r)
+func TestPlain(t *testing.T) {
+	tests := []struct {
+		help   string
+		expect string
+	}{
+		{"Set the status to *STATUS*. See **VOLUME STATUS** section.", "Set the status to STATUS. See VOLUME STATUS section."},
+		{"Use `--json` or see [the docs](https://example.org).", "Use --json or see the docs."},
+		{"Matches 2 * 3 and\nmore.", "Matches 2 * 3 and more."},
+	}
+	for _, tc := range tests {
+		if got := plain(tc.help); got != tc.expect {
+			t.Errorf("for %q, expected %q, got %q", tc.help, tc.expect, got)
 		}
-	}()
-	parser := kong.Must(&T5{})
-	z := &Zsh{}
-	z.Completion(parser.Model.Node, "t5")
+	}
 }
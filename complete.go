@@ -0,0 +1,285 @@
+package king
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/alecthomas/kong"
+)
+
+// CompleteName is the name of the hidden command that the shims, as generated by the completers when Dynamic
+// is true, call to get the completions.
+const CompleteName = "__complete"
+
+// Candidate is a single completion as returned by [Complete].
+type Candidate struct {
+	Value       string
+	Description string
+}
+
+// CompleteCmd is a kong command that prints the completions for the words given to it. It must be added to the
+// application as a hidden command named [CompleteName], either with [CompleteOption] or via a struct field:
+//
+//	type CLI struct {
+//		Complete king.CompleteCmd `cmd:"" name:"__complete" hidden:""`
+//	}
+//
+// Each candidate is printed on a line as: value, tab, description. If the shell should complete an action
+// (see [Complete]) the last line is ":<action>".
+//
+// The completions are printed, and the application exits, while kong parses the command line: before it
+// checks the required flags and arguments of the application, as these aren't given when completing.
+type CompleteCmd struct {
+	Words []string `arg:"" optional:"" passthrough:"" help:"Words on the command line, the last one is completed."`
+
+	done bool // completions are printed by BeforeReset
+}
+
+// BeforeReset is the kong hook that prints the completions and exits, before kong validates the command line.
+func (c *CompleteCmd) BeforeReset(ctx *kong.Context) error {
+	if i := slices.Index(ctx.Args, CompleteName); i >= 0 {
+		c.Words = ctx.Args[i+1:]
+	}
+	if err := c.Run(ctx); err != nil {
+		return err
+	}
+	c.done = true
+	ctx.Exit(0)
+	return nil
+}
+
+// Run implements the command, it prints the completions to ctx.Stdout.
+func (c *CompleteCmd) Run(ctx *kong.Context) error {
+	if c.done {
+		return nil
+	}
+	words := c.Words
+	if len(words) > 0 && words[0] == "--" { // passthrough keeps the "--"
+		words = words[1:]
+	}
+	cands, action := Complete(ctx.Model.Node, words)
+	for _, cand := range cands {
+		fmt.Fprintf(ctx.Stdout, "%s\t%s\n", cand.Value, cand.Description)
+	}
+	if action != "" {
+		fmt.Fprintf(ctx.Stdout, ":%s\n", action)
+	}
+	return nil
+}
+
+// CompleteOption returns a kong option that adds the hidden [CompleteCmd] to the application.
+func CompleteOption() kong.Option {
+	return kong.DynamicCommand(CompleteName, "Complete the command line.", "", &CompleteCmd{}, `hidden:""`)
+}
+
+// Complete returns the completions for the words as typed on the command line, the first word (the name of
+// the executable) should not be included. The last word is the one being completed, this may be the empty
+// string. The model in k is walked to find the command, flag or positional argument that is being completed.
+// If the completion is done via one of the actions ("file", "directory", etc.) this is returned in action and
+// it is up to the shell to complete this.
+func Complete(k *kong.Node, words []string) (cands []Candidate, action string) {
+	if len(words) == 0 {
+		words = []string{""}
+	}
+	cur := k
+	pos := 0
+	var value *kong.Flag // flag that needs a value, as the previous word
+	given := []*kong.Flag{}
+	values := map[string][]string{} // values of the given flags, for the predictors
+	dashdash := false
+	for _, w := range words[:len(words)-1] {
+		if value != nil {
+			values[value.Name] = append(values[value.Name], w)
+			value = nil
+			continue
+		}
+		switch {
+		case dashdash:
+			pos++
+		case w == "--":
+			dashdash = true
+		case strings.HasPrefix(w, "-"):
+			f := findFlag(cur, w)
+			if f == nil {
+				continue
+			}
+			given = append(given, f)
+			_, val, ok := strings.Cut(w, "=")
+			switch {
+			case ok:
+				values[f.Name] = append(values[f.Name], val)
+			case takesValue(f):
+				value = f
+			default:
+				values[f.Name] = append(values[f.Name], strconv.FormatBool(w != negation(f)))
+			}
+		default:
+			if c := findCommand(cur, w); c != nil {
+				cur = c
+				pos = 0
+				continue
+			}
+			pos++
+		}
+	}
+
+	word := words[len(words)-1]
+	switch {
+	case value != nil:
+		return completeFlag(Args{word, words, cur, value.Value, values}, flagEnvs(value), "")
+	case !dashdash && strings.HasPrefix(word, "-") && strings.Contains(word, "="):
+		name, val, _ := strings.Cut(word, "=")
+		if f := findFlag(cur, name); f != nil {
+			return completeFlag(Args{val, words, cur, f.Value, values}, flagEnvs(f), name+"=")
+		}
+		return nil, ""
+	case !dashdash && strings.HasPrefix(word, "-"):
+		for n := cur; n != nil; n = n.Parent {
+			for _, f := range n.Flags {
+				if f.Hidden || excluded(f, n.Flags, given) {
+					continue
+				}
+				cands = append(cands, Candidate{"--" + f.Name, flagHelp(f)})
+				if f.Short != 0 {
+					cands = append(cands, Candidate{fmt.Sprintf("-%c", f.Short), flagHelp(f)})
+				}
+				if neg := negation(f); neg != "" {
+					cands = append(cands, Candidate{neg, flagHelp(f)})
+				}
+			}
+		}
+		return filter(cands, word), ""
+	}
+
+	if !dashdash {
+		for _, c := range cur.Children {
+			if c == nil || c.Hidden {
+				continue
+			}
+			for _, name := range append([]string{c.Name}, c.Aliases...) {
+				cands = append(cands, Candidate{name, plain(c.Help)})
+			}
+		}
+	}
+	cands = filter(cands, word)
+	if p := positional(cur, pos); p != nil {
+		more, action := completeValue(Args{word, words, cur, p, values}, nil, "")
+		return append(cands, more...), action
+	}
+	return cands, ""
+}
+
+// excluded returns true if f can't be given anymore, because it or a flag it is exclusive with is already
+// given, see [exclusive].
+func excluded(f *kong.Flag, flags, given []*kong.Flag) bool {
+	for _, x := range exclusive(f, flags) {
+		if slices.Contains(given, x) {
+			return true
+		}
+	}
+	return false
+}
+
+// findFlag returns the flag named by the word w, it looks in cmd and all its parents.
+func findFlag(cmd *kong.Node, w string) *kong.Flag {
+	name, _, _ := strings.Cut(w, "=")
+	for n := cmd; n != nil; n = n.Parent {
+		for _, f := range n.Flags {
+			if "--"+f.Name == name || (f.Short != 0 && fmt.Sprintf("-%c", f.Short) == name) || negation(f) == name {
+				return f
+			}
+		}
+	}
+	return nil
+}
+
+// findCommand returns the child of cmd named, or aliased, as w.
+func findCommand(cmd *kong.Node, w string) *kong.Node {
+	for _, c := range cmd.Children {
+		if c == nil || c.Type != kong.CommandNode || c.Hidden {
+			continue
+		}
+		if c.Name == w {
+			return c
+		}
+		for _, a := range c.Aliases {
+			if a == w {
+				return c
+			}
+		}
+	}
+	return nil
+}
+
+// completeFlag returns the completions for the value of a flag. For maps the keys are completed first, with a
+// '=' appended, as the completion tag completes the keys. Once the key is given only a predictor is asked for
+// the values, it gets the entire "key=value" in a.Word.
+func completeFlag(a Args, envs []string, prefix string) (cands []Candidate, action string) {
+	if !isMap(a.Value) {
+		return completeValue(a, envs, prefix)
+	}
+	if !strings.Contains(a.Word, "=") {
+		cands, action = completeValue(a, envs, prefix)
+		for i := range cands {
+			cands[i].Value += "="
+		}
+		return cands, action
+	}
+	if p := predictor(a.Value.Tag.Get("completion")); p != nil {
+		cands = filter(p.Predict(a), a.Word)
+		for i := range cands {
+			cands[i].Value = prefix + cands[i].Value
+		}
+	}
+	return cands, ""
+}
+
+// completeValue returns the completions for the value a.Value, each completion is prefixed with prefix.
+func completeValue(a Args, envs []string, prefix string) (cands []Candidate, action string) {
+	v := a.Value
+	for _, e := range v.EnumSlice() {
+		if strings.TrimSpace(e) != "" {
+			cands = append(cands, Candidate{Value: e})
+		}
+	}
+	if comp := v.Tag.Get("completion"); comp != "" {
+		switch {
+		case strings.HasPrefix(comp, "<") && strings.HasSuffix(comp, ">"):
+			action = comp[1 : len(comp)-1]
+		case strings.HasPrefix(comp, "@"):
+			if p := predictor(comp); p != nil {
+				cands = append(cands, p.Predict(a)...)
+			}
+		default:
+			out, _ := exec.Command("sh", "-c", comp).Output()
+			for _, f := range strings.Fields(string(out)) {
+				cands = append(cands, Candidate{Value: f})
+			}
+		}
+	}
+	for _, env := range envs {
+		if e := os.Getenv(env); e != "" {
+			cands = append(cands, Candidate{Value: e})
+		}
+	}
+	cands = filter(cands, a.Word)
+	for i := range cands {
+		cands[i].Value = prefix + cands[i].Value
+	}
+	return cands, action
+}
+
+// filter returns the candidates that start with prefix.
+func filter(cands []Candidate, prefix string) []Candidate {
+	filtered := cands[:0]
+	for _, c := range cands {
+		if strings.HasPrefix(c.Value, prefix) {
+			filtered = append(filtered, c)
+		}
+	}
+	return filtered
+}
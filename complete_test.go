@@ -0,0 +1,172 @@
+package king
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/alecthomas/kong"
+)
+
+func TestComplete(t *testing.T) {
+	parser := kong.Must(&T{})
+	tests := []struct {
+		words  []string
+		expect string
+		action string
+	}{
+		{[]string{""}, "do d more again even-more more", ""},
+		{[]string{"ev"}, "even-more", ""},
+		{[]string{"--"}, "--help", ""},
+		{[]string{"do", "--st"}, "--status", ""},
+		{[]string{"do", "--status", "s"}, "setup", ""},
+		{[]string{"do", "-s", ""}, "ok setup dst archive rm", ""},
+		{[]string{"d", "--status=a"}, "--status=archive", ""},
+		{[]string{"do", "--file", ""}, "", "file"},
+		{[]string{"do", ""}, "a b c", ""},
+		{[]string{"do", "--status", "ok", "b"}, "b", ""},
+		{[]string{"do", "a", ""}, "", ""},
+		{[]string{"even-more", "do-even-more", "--s"}, "--string --status", ""},
+	}
+	for i := range tests {
+		cands, action := Complete(parser.Model.Node, tests[i].words)
+		values := []string{}
+		for _, c := range cands {
+			values = append(values, c.Value)
+		}
+		if got := strings.Join(values, " "); got != tests[i].expect {
+			t.Errorf("test %d, expected %q, got %q", i, tests[i].expect, got)
+		}
+		if action != tests[i].action {
+			t.Errorf("test %d, expected action %q, got %q", i, tests[i].action, action)
+		}
+	}
+}
+
+func TestCompleteCmd(t *testing.T) {
+	type CLI struct {
+		T
+		Complete CompleteCmd `cmd:"" name:"__complete" hidden:""`
+	}
+	stdout := &bytes.Buffer{}
+	parser := kong.Must(&CLI{}, kong.Writers(stdout, stdout), kong.Exit(func(int) {}))
+	ctx, err := parser.Parse([]string{CompleteName, "--", "do", "--status", "s"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := ctx.Run(); err != nil {
+		t.Fatal(err)
+	}
+	if got := stdout.String(); got != "setup\t\n" {
+		t.Errorf("expected %q, got %q", "setup\t\n", got)
+	}
+}
+
+type Req struct {
+	Token  string   `required:"" help:"The API token."`
+	Server struct{} `cmd:"" help:"Manage servers."`
+	Secret struct {
+		Force bool `help:"Force it."`
+	} `cmd:"" hidden:"" help:"Do secret things."`
+}
+
+func TestCompleteRequired(t *testing.T) {
+	stdout := &bytes.Buffer{}
+	code := -1
+	parser := kong.Must(&Req{}, CompleteOption(), kong.Writers(stdout, stdout), kong.Exit(func(c int) { code = c; panic("exit") }))
+	func() {
+		defer func() { recover() }()
+		parser.Parse([]string{CompleteName, "--", ""})
+	}()
+	if code != 0 {
+		t.Errorf("expected exit code 0, got %d: %s", code, stdout)
+	}
+	if got := stdout.String(); got != "server\tManage servers.\n" {
+		t.Errorf("expected %q, got %q", "server\tManage servers.\n", got)
+	}
+}
+
+func TestCompleteHidden(t *testing.T) {
+	parser := kong.Must(&Req{})
+	cands, _ := Complete(parser.Model.Node, []string{"secret", "--"})
+	values := []string{}
+	for _, c := range cands {
+		values = append(values, c.Value)
+	}
+	if got := strings.Join(values, " "); got != "--help --token" {
+		t.Errorf("expected the flags of the application for a hidden command, got %q", got)
+	}
+}
+
+func TestCompleteRepeatable(t *testing.T) {
+	parser := kong.Must(&R{})
+	tests := []struct {
+		words  []string
+		expect string
+	}{
+		{[]string{"--"}, "--help --verbose --tag --label --status"},
+		{[]string{"--status", "ok", "-v", "--tag", "a", "--"}, "--help --verbose --tag --label"},
+		{[]string{"--label", ""}, "env= team="},
+		{[]string{"--label=t"}, "--label=team="},
+		{[]string{"--label", "env="}, ""},
+	}
+	for i := range tests {
+		cands, _ := Complete(parser.Model.Node, tests[i].words)
+		values := []string{}
+		for _, c := range cands {
+			values = append(values, c.Value)
+		}
+		if got := strings.Join(values, " "); got != tests[i].expect {
+			t.Errorf("test %d, expected %q, got %q", i, tests[i].expect, got)
+		}
+	}
+}
+
+func TestCompleteVariadic(t *testing.T) {
+	parser := kong.Must(&V{})
+	for _, words := range [][]string{{"rm", ""}, {"rm", "srv", ""}, {"rm", "srv", "a", "b", ""}} {
+		cands, _ := Complete(parser.Model.Node, words)
+		if words[1] == "" {
+			if len(cands) != 0 {
+				t.Errorf("expected no completions for the server, got %v", cands)
+			}
+			continue
+		}
+		if len(cands) != 2 || cands[0].Value != "a" || cands[1].Value != "b" {
+			t.Errorf("expected volumes for %q, got %v", words, cands)
+		}
+	}
+}
+
+// TestHelperProcess isn't a real test, it runs the application with the fixture G and the hidden complete command,
+// so the shims can be tested in the real shell. It is called as: g() { KING_HELPER=1 <test binary>
+// -test.run=TestHelperProcess -- "$@"; }.
+func TestHelperProcess(t *testing.T) {
+	if os.Getenv("KING_HELPER") != "1" {
+		return
+	}
+	args := os.Args
+	for len(args) > 0 && args[0] != "--" {
+		args = args[1:]
+	}
+	parser := kong.Must(&G{}, kong.Name("g"), CompleteOption())
+	ctx, err := parser.Parse(args[1:])
+	if err != nil {
+		os.Exit(2)
+	}
+	if err := ctx.Run(); err != nil {
+		os.Exit(1)
+	}
+	os.Exit(0)
+}
+
+// helper returns the shell function that runs the application as g, see [TestHelperProcess].
+func helper(t *testing.T) string {
+	exe, err := os.Executable()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return fmt.Sprintf("g() { KING_HELPER=1 %q -test.run=TestHelperProcess -- \"$@\"; }\n", exe)
+}
@@ -0,0 +1,123 @@
+package king
+
+import (
+	"cmp"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/alecthomas/kong"
+)
+
+// Problem is a single problem found in a kong node tree.
+type Problem struct {
+	Path  string // Path of the command, as in "c volume list".
+	Field string // Flag ("--status") or positional argument ("VOLUME") that has the problem, may be empty.
+	Tag   string // The offending tag, as in `completion:"<file>"`, may be empty.
+	Msg   string // What is wrong.
+}
+
+func (p Problem) String() string {
+	s := &strings.Builder{}
+	s.WriteString(p.Path)
+	if p.Field != "" {
+		s.WriteString(" " + p.Field)
+	}
+	if p.Tag != "" {
+		s.WriteString(" " + p.Tag)
+	}
+	s.WriteString(": " + p.Msg)
+	return s.String()
+}
+
+// Error is returned when generating completions or manual pages if the kong node tree has problems. It lists
+// every problem that was found.
+type Error struct {
+	Problems []Problem
+}
+
+func (e *Error) Error() string {
+	if len(e.Problems) == 1 {
+		return "king: " + e.Problems[0].String()
+	}
+	s := &strings.Builder{}
+	fmt.Fprintf(s, "king: %d problems:", len(e.Problems))
+	for _, p := range e.Problems {
+		s.WriteString("\n\t" + p.String())
+	}
+	return s.String()
+}
+
+// problems returns an *Error with the sorted problems if there are any, otherwise nil.
+func problems(ps []Problem) error {
+	if len(ps) == 0 {
+		return nil
+	}
+	sortProblems(ps)
+	return &Error{Problems: ps}
+}
+
+// sortProblems sorts the problems on path, field and tag.
+func sortProblems(ps []Problem) {
+	slices.SortStableFunc(ps, func(a, b Problem) int {
+		return cmp.Or(cmp.Compare(a.Path, b.Path), cmp.Compare(a.Field, b.Field), cmp.Compare(a.Tag, b.Tag))
+	})
+}
+
+// tag returns the tag key:"value" as it would be written in Go.
+func tag(key, value string) string { return fmt.Sprintf("%s:%q", key, value) }
+
+// check checks the node tree starting at k, with the extra flags added to k, for problems that prevent the
+// generation of a completion. All problems in the tree are returned in a single *[Error].
+func check(k *kong.Node, flags []*kong.Flag) error {
+	ps := nodeProblems(k, commandPath(k, " "), append(slices.Clone(k.Flags), flags...))
+	var walk func(n *kong.Node)
+	walk = func(n *kong.Node) {
+		for _, c := range n.Children {
+			if c != nil {
+				ps = append(ps, nodeProblems(c, commandPath(c, " "), c.Flags)...)
+				walk(c)
+			}
+		}
+	}
+	walk(k)
+	return problems(ps)
+}
+
+// checkManual checks cmd for problems that prevent the generation of a manual page.
+func checkManual(cmd *kong.Node, path string) error {
+	return problems(nodeProblems(cmd, path, cmd.Flags))
+}
+
+// nodeProblems returns the problems of the node n, with the flags and its positional arguments, that prevent
+// the generation of completions and manual pages. [Lint] reports these as well.
+func nodeProblems(n *kong.Node, path string, flags []*kong.Flag) []Problem {
+	ps := []Problem{}
+	if _, bad := exitCodes(n); len(bad) > 0 {
+		ps = append(ps, Problem{path, "", tag("exitcodes", n.Tag.Get("exitcodes")), "exit codes must be written as code=meaning, with code in 0-255"})
+	}
+	for _, f := range flags {
+		field := "--" + f.Name
+		if comp := f.Tag.Get("completion"); comp != "" && f.IsBool() {
+			ps = append(ps, Problem{path, field, tag("completion", comp), "a boolean flag can not have completion"})
+		}
+		if f.Group != nil && f.Group.Key != strings.ToLower(f.Group.Key) {
+			ps = append(ps, Problem{path, field, tag("group", f.Group.Key), "group keys must be all lowercase"})
+		}
+		ps = append(ps, actionProblems(f.Value, path, field)...)
+	}
+	for _, p := range n.Positional {
+		ps = append(ps, actionProblems(p, path, strings.ToUpper(p.Name))...)
+	}
+	return ps
+}
+
+// actionProblems reports a completion tag with an unknown action on v.
+func actionProblems(v *kong.Value, path, field string) []Problem {
+	if comp := v.Tag.Get("completion"); strings.HasPrefix(comp, "<") && strings.HasSuffix(comp, ">") {
+		if _, ok := zshActions[comp[1:len(comp)-1]]; !ok {
+			return []Problem{{path, field, tag("completion", comp), "unknown action"}}
+		}
+	}
+	return nil
+}
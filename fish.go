@@ -14,6 +14,8 @@ type Fish struct {
 	name       string
 	completion []byte
 	Flags      []*kong.Flag // Any global flags that the should Application Node have.
+	Dynamic    bool         // If true only a shim is generated that calls the [CompleteName] command of the application.
+	Validate   bool         // If true Write checks the completion with [Validate] first.
 }
 
 func (f *Fish) Out() []byte { return f.completion }
@@ -22,15 +24,22 @@ func (f *Fish) Write(w ...io.Writer) error {
 	if f.completion == nil {
 		return fmt.Errorf("no completion")
 	}
+	if f.Validate {
+		if err := Validate("fish", f.completion); err != nil {
+			return err
+		}
+	}
 	if len(w) > 0 {
 		w[0].Write(f.completion)
 	}
 	return os.WriteFile(f.name+".fish", f.completion, 0644) // no idea what fish needs
 }
 
-func (f *Fish) Completion(k *kong.Node, altname string) {
-	k.Flags = append(k.Flags, f.Flags...)
-
+func (f *Fish) Completion(k *kong.Node, altname string) error {
+	if err := check(k, f.Flags); err != nil {
+		return err
+	}
+	addFlags(k, f.Flags)
 	format := `# fish shell completion for %[1]s
 # generated by king (https://github.com/miekg/king) for kong
 `
@@ -43,53 +52,244 @@ func (f *Fish) Completion(k *kong.Node, altname string) {
 		k.Name = altname
 	}
 	fmt.Fprintf(&out, format, f.name)
-	f.gen(&out, k)
+	if f.Dynamic {
+		f.shim(&out, k)
+	} else {
+		f.gen(&out, k)
+	}
 	f.completion = []byte(out.String())
+	return nil
 }
 
-func (f Fish) gen(buf io.StringWriter, cmd *kong.Node) {
-	root := cmd
-	for root.Parent != nil {
-		root = root.Parent
+// fishQuote returns s as a single quoted fish string.
+func fishQuote(s string) string {
+	s = strings.ReplaceAll(s, `\`, `\\`)
+	return "'" + strings.ReplaceAll(s, "'", `\'`) + "'"
+}
+
+// fishEscape returns s escaped with backslashes, so fish sees it as a literal word when it expands the arguments
+// of complete's -a.
+func fishEscape(s string) string {
+	e := &strings.Builder{}
+	for _, r := range s {
+		if !safe(r) {
+			e.WriteRune('\\')
+		}
+		e.WriteRune(r)
 	}
-	rootName := root.Name
-	if cmd.Parent == nil {
-		buf.WriteString(fmt.Sprintf("# %s\n", rootName))
-	} else {
-		buf.WriteString(fmt.Sprintf("# %s\n", cmd.Path()))
-		buf.WriteString(fmt.Sprintf("complete -c %s -f -n '__fish_use_subcommand' -a %s -d '%s'\n", rootName, cmd.Name, cmd.Help))
+	return e.String()
+}
+
+// values returns the arguments for complete's -a for the value v. For maps the shell command completes the keys,
+// these get a '=' appended.
+func (f Fish) values(v *kong.Value, envs []string) []string {
+	values := []string{}
+	for _, e := range v.EnumSlice() {
+		if strings.TrimSpace(e) != "" {
+			values = append(values, fishEscape(e))
+		}
+	}
+	if comp := completion(v, "fish"); comp != "" {
+		if strings.HasPrefix(comp, "$(") { // shell command
+			if isMap(v) {
+				comp = "(" + comp[2:len(comp)-1] + ` | string split -n " " | string replace -r '$' =)`
+			} else {
+				comp = "(" + comp[2:len(comp)-1] + ` | string split -n " ")`
+			}
+		}
+		values = append(values, comp)
 	}
+	for _, env := range envs {
+		values = append(values, "$"+env)
+	}
+	return values
+}
 
-	for _, f := range cmd.Flags {
-		if f.Hidden {
+func (f Fish) writeCases(buf io.StringWriter, cmd *kong.Node) {
+	path := commandPath(cmd, " ")
+	for _, c := range cmd.Children {
+		if c == nil || c.Hidden {
 			continue
 		}
-		if cmd.Parent == nil {
-			buf.WriteString(fmt.Sprintf("complete -c %s -f", rootName))
-		} else {
-			buf.WriteString(fmt.Sprintf("complete -c %s -f -n '__fish_seen_subcommand_from %s'", rootName, cmd.Name))
+		cases := []string{}
+		for _, name := range append([]string{c.Name}, c.Aliases...) {
+			cases = append(cases, fishQuote(path+" "+name))
+		}
+		writeString(buf, fmt.Sprintf("            case %s\n", strings.Join(cases, " ")))
+		writeString(buf, fmt.Sprintf("                set cmd %s\n", fishQuote(commandPath(c, " "))))
+		writeString(buf, "                set pos 0\n")
+	}
+	for _, fl := range cmd.Flags {
+		if fl.Hidden || fl.IsBool() || fl.IsCounter() {
+			continue
+		}
+		// a flag can also be given after a subcommand: 'c * --flag'
+		cases := []string{fishQuote(path + " --" + fl.Name), fishQuote(path + " * --" + fl.Name)}
+		if fl.Short != 0 {
+			cases = append(cases, fishQuote(fmt.Sprintf("%s -%c", path, fl.Short)), fishQuote(fmt.Sprintf("%s * -%c", path, fl.Short)))
 		}
-		if !f.IsBool() {
-			enums := flagEnums(f)
-			if len(enums) > 0 {
-				buf.WriteString(fmt.Sprintf(" -xa '%s'", strings.Join(enums, " ")))
+		writeString(buf, fmt.Sprintf("            case %s\n", strings.Join(cases, " ")))
+		writeString(buf, "                set skip 1\n")
+	}
+	for _, c := range cmd.Children {
+		if c == nil || c.Hidden {
+			continue
+		}
+		f.writeCases(buf, c)
+	}
+}
+
+// writeState writes the functions that walk the command line to find the current command and the index of
+// the positional argument.
+func (f Fish) writeState(buf io.StringWriter, cmd *kong.Node) {
+	cases := &strings.Builder{}
+	f.writeCases(cases, cmd)
+	format := `function __%[1]s_state
+    set -l cmd %[2]s
+    set -l pos 0
+    set -l skip 0
+    set -l tokens (commandline -opc)
+    set -e tokens[1]
+    for w in $tokens
+        if test $skip -eq 1
+            set skip 0
+            continue
+        end
+        switch "$cmd $w"
+%[3]s            case '* -*'
+            case '*'
+                set pos (math $pos + 1)
+        end
+    end
+    echo $cmd
+    echo $pos
+end
+
+function __%[1]s_command
+    set -l state (__%[1]s_state)
+    test "$state[1]" = "$argv[1]"
+end
+
+function __%[1]s_positional
+    set -l state (__%[1]s_state)
+    test "$state[1]" = "$argv[1]" -a "$state[2]" = "$argv[2]"
+end
+
+function __%[1]s_positional_from
+    set -l state (__%[1]s_state)
+    test "$state[1]" = "$argv[1]" -a "$state[2]" -ge "$argv[2]"
+end
+
+`
+	writeString(buf, fmt.Sprintf(format, f.name, fishQuote(cmd.Name), cases.String()))
+}
+
+// seen returns the condition, to be added to cond, that is true when none of the flags that exclude fl have been
+// given, see [exclusive]. For repeatable flags that aren't in a xor group cond is returned as is.
+func (f Fish) seen(cond string, fl *kong.Flag, flags []*kong.Flag) string {
+	args := []string{}
+	for _, x := range exclusive(fl, flags) {
+		for _, name := range flagNames(x) {
+			if strings.HasPrefix(name, "--") {
+				args = append(args, "-l "+name[2:])
 			} else {
-				buf.WriteString(" -x")
+				args = append(args, "-s "+name[1:])
 			}
 		}
-		if f.Short != 0 {
-			buf.WriteString(fmt.Sprintf(" -s %c", f.Short))
+	}
+	if len(args) == 0 {
+		return cond
+	}
+	return cond + "; and not __fish_seen_argument " + strings.Join(args, " ")
+}
+
+func (f Fish) writeFlag(buf io.StringWriter, fl *kong.Flag, cond string) {
+	writeString(buf, fmt.Sprintf("complete -c %s -n %s", f.name, cond))
+	if !fl.IsBool() && !fl.IsCounter() {
+		if values := f.values(fl.Value, flagEnvs(fl)); len(values) > 0 {
+			writeString(buf, fmt.Sprintf(" -x -a %s", fishQuote(strings.Join(values, " "))))
+		} else {
+			writeString(buf, " -x")
 		}
-		buf.WriteString(fmt.Sprintf(" -l %s", f.Name))
-		buf.WriteString(fmt.Sprintf(" -d \"%s\"", f.Help))
-		buf.WriteString("\n")
 	}
-	buf.WriteString("\n")
+	if fl.Short != 0 {
+		writeString(buf, fmt.Sprintf(" -s %c", fl.Short))
+	}
+	writeString(buf, fmt.Sprintf(" -l %s -d %s\n", fl.Name, fishQuote(flagHelp(fl))))
+	if neg := negation(fl); neg != "" {
+		writeString(buf, fmt.Sprintf("complete -c %s -n %s -l %s -d %s\n", f.name, cond, neg[2:], fishQuote(flagHelp(fl))))
+	}
+}
 
+func (f Fish) writeCommand(buf io.StringWriter, cmd *kong.Node) {
+	path := commandPath(cmd, " ")
+	cond := fmt.Sprintf(`"__%s_command '%s'"`, f.name, path)
+	writeString(buf, fmt.Sprintf("# %s\n", path))
 	for _, c := range cmd.Children {
 		if c == nil || c.Hidden {
 			continue
 		}
-		f.gen(buf, c)
+		for _, name := range append([]string{c.Name}, c.Aliases...) {
+			writeString(buf, fmt.Sprintf("complete -c %s -f -n %s -a %s -d %s\n", f.name, cond, name, fishQuote(commandHelp(c))))
+		}
+	}
+	for _, fl := range cmd.Flags {
+		if fl.Hidden {
+			continue
+		}
+		f.writeFlag(buf, fl, `"`+f.seen(fmt.Sprintf(`__%s_command '%s'`, f.name, path), fl, cmd.Flags)+`"`)
 	}
+	for i, p := range cmd.Positional {
+		values := f.values(p, nil)
+		if len(values) == 0 {
+			continue
+		}
+		pcond := fmt.Sprintf(`"__%s_positional '%s' %d"`, f.name, path, i)
+		if variadic(p) {
+			pcond = fmt.Sprintf(`"__%s_positional_from '%s' %d"`, f.name, path, i)
+		}
+		writeString(buf, fmt.Sprintf("complete -c %s -f -n %s -a %s -d %s\n", f.name, pcond, fishQuote(strings.Join(values, " ")), fishQuote(plain(p.Help))))
+	}
+	writeString(buf, "\n")
+
+	for _, c := range cmd.Children {
+		if c == nil || c.Hidden {
+			continue
+		}
+		f.writeCommand(buf, c)
+	}
+}
+
+func (f Fish) gen(buf io.StringWriter, cmd *kong.Node) {
+	writeString(buf, fmt.Sprintf("\ncomplete -c %s -f\n\n", f.name))
+	f.writeState(buf, cmd)
+	f.writeCommand(buf, cmd)
+}
+
+// shim writes a completion function that calls the application to get the completions.
+func (f Fish) shim(buf io.StringWriter, cmd *kong.Node) {
+	format := `function __%[1]s_complete
+    set -l tokens (commandline -opc) (commandline -ct)
+    set -e tokens[1]
+    for line in (%[1]s %[2]s -- $tokens 2>/dev/null)
+        switch $line
+            case ':file'
+                __fish_complete_path (commandline -ct)
+            case ':directory'
+                __fish_complete_directories (commandline -ct)
+            case ':user'
+                __fish_complete_users
+            case ':group'
+                __fish_complete_groups
+            case ':export'
+                set -n
+            case '*'
+                echo $line
+        end
+    end
+end
+
+complete -c %[1]s -f -a '(__%[1]s_complete)'
+`
+	buf.WriteString(fmt.Sprintf(format, f.name, CompleteName))
 }
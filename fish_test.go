@@ -1,18 +1,152 @@
 package king
 
 import (
+	"bytes"
+	"os/exec"
+	"strings"
 	"testing"
 
 	"github.com/alecthomas/kong"
+	"github.com/miekg/king/internal/comptest"
 )
 
-func compfTest(t *testing.T, completionfile, exe string) {
-	// test actual completion ala zsh
+// compfTest returns the completions fish gives for line with the completion script, the completions are joined
+// with a space. Any descriptions are stripped.
+func compfTest(t *testing.T, script []byte, line string) string {
+	t.Helper()
+	if _, err := exec.LookPath("fish"); err != nil {
+		t.Skip("fish not found")
+	}
+	completions, err := comptest.Fish(script, line)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return strings.Join(completions, " ")
 }
 
 func TestFish(t *testing.T) {
 	parser := kong.Must(&T{})
+	f := &Fish{Flags: []*kong.Flag{manf}}
+	if err := f.Completion(parser.Model.Node, "myexe"); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		exe    string
+		expect string
+	}{
+		{"myexe --", "--help --man"},
+		{"myexe ev", "even-more"},
+		{"myexe do --st", "--status"},
+		{"myexe do --status s", "setup"},
+		{"myexe d -s ok ", "a b c"},
+		{"myexe d -s ok a ", ""},
+		{"myexe even-more do-even-more --s", "--string"},
+		{"myexe do --super-string ", "bla bloep"},
+	}
+
+	for i := range tests {
+		out := compfTest(t, f.Out(), tests[i].exe)
+		if out != tests[i].expect {
+			t.Errorf("test %d, expected %q, got %q", i, tests[i].expect, out)
+		}
+	}
+}
+
+func TestFishExclusive(t *testing.T) {
+	parser := kong.Must(&X{})
+	f := &Fish{}
+	if err := f.Completion(parser.Model.Node, "x"); err != nil {
+		t.Fatal(err)
+	}
+	expect := `complete -c x -n "__x_command 'x'; and not __fish_seen_argument -l json -l yaml -l no-yaml" -l json`
+	if !bytes.Contains(f.Out(), []byte(expect)) {
+		t.Errorf("expected %s in completion, got\n%s", expect, f.Out())
+	}
+}
+
+func TestFishHostile(t *testing.T) {
+	parser := kong.Must(&H{})
+	f := &Fish{}
+	if err := f.Completion(parser.Model.Node, "h"); err != nil {
+		t.Fatal(err)
+	}
+	for _, expect := range []string{
+		`-a 'it\\\'s a\\"b \\$HOME a\\[1\\] a:b a\\ b \\(x\\) \\` + "`" + `date\\` + "`" + `' -l quote -d 'It\'s a "quote" with $HOME, [brackets], a: colon and date.'`,
+		`-a cmd -d 'It\'s a [command]: with "quotes", $HOME and date.'`,
+	} {
+		if !bytes.Contains(f.Out(), []byte(expect)) {
+			t.Errorf("expected %s in completion, got\n%s", expect, f.Out())
+		}
+	}
+	if out := compfTest(t, f.Out(), "h --quote $H"); out != "$HOME" {
+		t.Errorf("expected %q, got %q", "$HOME", out)
+	}
+}
+
+func TestFishParentFlag(t *testing.T) {
+	parser := kong.Must(&G{})
+	f := &Fish{}
+	if err := f.Completion(parser.Model.Node, "g"); err != nil {
+		t.Fatal(err)
+	}
+	expect := `            case 'g --config' 'g * --config' 'g -c' 'g * -c'`
+	if !bytes.Contains(f.Out(), []byte(expect)) {
+		t.Errorf("expected %s in completion, got\n%s", expect, f.Out())
+	}
+	if out := compfTest(t, f.Out(), "g server --config x "); out != "web:data db:logs" {
+		t.Errorf("expected %q, got %q", "web:data db:logs", out)
+	}
+}
+
+func TestFishDeprecated(t *testing.T) {
+	parser := kong.Must(&D{})
+	f := &Fish{}
+	if err := f.Completion(parser.Model.Node, "d"); err != nil {
+		t.Fatal(err)
+	}
+	for _, expect := range []string{
+		`-l json -d '(deprecated: use --format=json) output json.'`,
+		`-l verbose -d '(deprecated) be verbose.'`,
+	} {
+		if !bytes.Contains(f.Out(), []byte(expect)) {
+			t.Errorf("expected %s in completion, got\n%s", expect, f.Out())
+		}
+	}
+	if out := compfTest(t, f.Out(), "d --j"); out != "--json" {
+		t.Errorf("expected %q, got %q", "--json", out)
+	}
+}
+
+func TestFishRepeatable(t *testing.T) {
+	parser := kong.Must(&R{})
+	f := &Fish{}
+	if err := f.Completion(parser.Model.Node, "r"); err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		line   string
+		expect string
+	}{
+		{"r -v --verb", "--verbose"},
+		{"r --tag a --ta", "--tag"},
+		{"r --status ok --st", ""},
+		{"r --label t", "team="},
+	}
+	for _, tc := range tests {
+		if got := compfTest(t, f.Out(), tc.line); got != tc.expect {
+			t.Errorf("for %q, expected %q, got %q", tc.line, tc.expect, got)
+		}
+	}
+}
+
+func TestFishVariadic(t *testing.T) {
+	parser := kong.Must(&V{})
 	f := &Fish{}
-	f.Completion(parser.Model.Node, "myexe")
-	println(string(f.Out()))
+	if err := f.Completion(parser.Model.Node, "v"); err != nil {
+		t.Fatal(err)
+	}
+	if out := compfTest(t, f.Out(), "v rm s1 a "); out != "a b" {
+		t.Errorf("expected %q, got %q", "a b", out)
+	}
 }
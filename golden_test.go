@@ -0,0 +1,131 @@
+package king
+
+import (
+	"bytes"
+	"flag"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/alecthomas/kong"
+)
+
+var update = flag.Bool("update", false, "update the golden files in testdata")
+
+// G is the fixture for the golden files, it should have all the features the generators support.
+type G struct {
+	Verbose int    `type:"counter" short:"v" help:"Be more verbose, can be repeated."`
+	Config  string `help:"Use this configuration file." env:"G_CONFIG" completion:"<file>" placeholder:"FILE" short:"c"`
+	Color   bool   `negatable:"" default:"true" help:"Use colors in the output."`
+	Debug   bool   `hidden:"" help:"Print debug information."`
+
+	Volume GVolume  `cmd:"" aliases:"vol" help:"Manage volumes." description:"Create, list and remove *volumes*." exitcodes:"0=ok,3=volume not found"`
+	Server GServer  `cmd:"" help:"Manage servers." seealso:"ssh(1)"`
+	Secret struct{} `cmd:"" hidden:"" help:"Do secret things."`
+}
+
+type GVolume struct {
+	List   GVolumeList   `cmd:"" aliases:"ls" default:"withargs" help:"List the volumes." examples:"g volume list --status ok # List the volumes that are ok."`
+	Create GVolumeCreate `cmd:"" help:"Create a volume." files:"/etc/g/volumes=the volume database"`
+}
+
+type GVolumeList struct {
+	Status string            `enum:"ok,setup,rm" default:"ok" short:"s" help:"Only list volumes with status *STATUS*." placeholder:"STATUS"`
+	JSON   bool              `xor:"format" group:"output" help:"Output in json."`
+	YAML   bool              `xor:"format" group:"output" help:"Output in yaml."`
+	Label  map[string]string `help:"Only list volumes with this label." completion:"echo team owner"`
+	Tag    []string          `help:"Only list volumes with this tag." completion:"echo blue green"`
+	Old    bool              `deprecated:"use --status rm" help:"List the removed volumes."`
+
+	Name string `arg:"" optional:"" help:"Only list the volume with this name." completion:"echo vol1 vol2"`
+}
+
+type GVolumeCreate struct {
+	Size    int      `required:"" env:"G_SIZE,G_VOLUME_SIZE" help:"Size of the volume in GB."`
+	Dir     string   `completion:"<directory>" help:"Directory to create the volume in."`
+	Name    string   `arg:"" help:"Name of the volume."`
+	Servers []string `arg:"" optional:"" help:"Servers to create the volume on." completion:"echo s1 s2"`
+}
+
+type GServer struct {
+	Region string `enum:"eu,us" default:"eu" env:"G_REGION" help:"Region of the server."`
+	Owner  string `completion:"<user>" help:"Owner of the server."`
+
+	Host string `arg:"" help:"Name of the server." completion:"echo web:data db:logs"`
+}
+
+// golden compares got with the contents of testdata/name, when -update is given the file is written instead.
+func golden(t *testing.T, name string, got []byte) {
+	t.Helper()
+	file := filepath.Join("testdata", name)
+	if *update {
+		if err := os.MkdirAll(filepath.Dir(file), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(file, got, 0644); err != nil {
+			t.Fatal(err)
+		}
+		return
+	}
+	expect, err := os.ReadFile(file)
+	if err != nil {
+		t.Fatalf("%s, run the tests with -update to create it", err)
+	}
+	if !bytes.Equal(got, expect) {
+		t.Errorf("output differs from %s, run the tests with -update and review the diff, got:\n%s", file, got)
+	}
+}
+
+func TestGoldenCompletion(t *testing.T) {
+	tests := []struct {
+		name      string
+		completer Completer
+	}{
+		{"g.bash", &Bash{}},
+		{"g-descriptions.bash", &Bash{Descriptions: true}},
+		{"g-dynamic.bash", &Bash{Dynamic: true}},
+		{"_g", &Zsh{}},
+		{"_g-dynamic", &Zsh{Dynamic: true}},
+		{"g.fish", &Fish{}},
+		{"g-dynamic.fish", &Fish{Dynamic: true}},
+		{"g.ps1", &PowerShell{}},
+		{"g-dynamic.ps1", &PowerShell{Dynamic: true}},
+		{"g.nu", &Nushell{}},
+		{"g-dynamic.nu", &Nushell{Dynamic: true}},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			parser := kong.Must(&G{})
+			if err := tc.completer.Completion(parser.Model.Node, "g"); err != nil {
+				t.Fatal(err)
+			}
+			golden(t, filepath.Join("completion", tc.name), tc.completer.Out())
+		})
+	}
+}
+
+func TestGoldenMan(t *testing.T) {
+	parser := kong.Must(&G{})
+	m := &Man{
+		Section:   1,
+		Area:      "User Commands",
+		WorkGroup: "The g team",
+		Flags:     []*kong.Flag{manf},
+		Authors:   []string{"Miek Gieben <miek@miek.nl>"},
+		Bugs:      "Report bugs at https://github.com/miekg/king/issues.",
+	}
+	pages, err := m.Tree(parser.Model.Node, "g")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, p := range pages {
+		t.Run(p.Name(), func(t *testing.T) {
+			golden(t, filepath.Join("man", p.Name()+".md"), p.Out())
+			roff := &bytes.Buffer{}
+			if err := p.Write(roff); err != nil {
+				t.Fatal(err)
+			}
+			golden(t, filepath.Join("man", p.Name()+".1"), roff.Bytes())
+		})
+	}
+}
@@ -0,0 +1,201 @@
+// Package comptest drives real shells to get the completions a generated completion script gives.
+package comptest
+
+import (
+	"bytes"
+	_ "embed"
+	"os"
+	"os/exec"
+	"strings"
+	"text/template"
+)
+
+// bashHarness sources the completion script, sets up the COMP_ variables as bash does, calls the function that is
+// registered for the command and prints COMPREPLY. As there is no real completion going on, compopt is a no-op.
+const bashHarness = `compopt() { :; }
+source "$1" || exit 1
+COMP_LINE=$2
+COMP_POINT=${#COMP_LINE}
+shift 2
+COMP_WORDS=("$@")
+COMP_CWORD=$(( $# - 1 ))
+spec=$(complete -p -- "${COMP_WORDS[0]}") || exit 1
+fn=${spec##*-F }
+fn=${fn%% *}
+COMPREPLY=()
+"$fn" "${COMP_WORDS[0]}" "${COMP_WORDS[COMP_CWORD]}" "${COMP_WORDS[COMP_CWORD-1]}"
+printf '%s\n' "${COMPREPLY[@]}"
+`
+
+// Bash returns the completions bash gives for line with the completion script. The line is split into words as
+// bash does, the last word is the one being completed, if line ends in a space this is the empty word.
+func Bash(script []byte, line string) ([]string, error) {
+	file, err := tempFile(script, "comptest-*.bash")
+	if err != nil {
+		return nil, err
+	}
+	defer os.Remove(file)
+
+	args := append([]string{"--norc", "--noprofile", "-c", bashHarness, "bash", file, line}, Words(line)...)
+	out, err := exec.Command("bash", args...).CombinedOutput()
+	if err != nil {
+		return nil, &Error{Shell: "bash", Err: err, Output: out}
+	}
+	return lines(out), nil
+}
+
+//go:embed comptest.zsh.tmpl
+var zshHarness string
+
+var zshTmpl = template.Must(template.New("comptest.zsh.tmpl").Parse(zshHarness))
+
+// Zsh returns the completions zsh gives for line with the completion script. The script is sourced after compinit
+// and the line is typed, followed by a TAB, in a pseudo terminal (zpty).
+func Zsh(script []byte, line string) ([]string, error) {
+	file, err := tempFile(script, "comptest-*.zsh")
+	if err != nil {
+		return nil, err
+	}
+	defer os.Remove(file)
+
+	harness := &bytes.Buffer{}
+	line = strings.NewReplacer(`\`, `\\`, `'`, `\'`).Replace(line) // for $'...'
+	if err := zshTmpl.Execute(harness, struct{ Compfile, Comptest string }{file, line}); err != nil {
+		return nil, err
+	}
+	test, err := tempFile(harness.Bytes(), "comptest-*.zsh")
+	if err != nil {
+		return nil, err
+	}
+	defer os.Remove(test)
+
+	out, err := exec.Command("zsh", "-f", test).CombinedOutput()
+	if err != nil {
+		return nil, &Error{Shell: "zsh", Err: err, Output: out}
+	}
+	return lines(out), nil
+}
+
+// Fish returns the completions fish gives for line with the completion script, the descriptions are stripped.
+func Fish(script []byte, line string) ([]string, error) {
+	file, err := tempFile(script, "comptest-*.fish")
+	if err != nil {
+		return nil, err
+	}
+	defer os.Remove(file)
+
+	out, err := exec.Command("fish", "--no-config", "-c", "source "+fishQuote(file)+"; complete -C "+fishQuote(line)).CombinedOutput()
+	if err != nil {
+		return nil, &Error{Shell: "fish", Err: err, Output: out}
+	}
+	completions := lines(out)
+	for i := range completions {
+		completions[i], _, _ = strings.Cut(completions[i], "\t")
+	}
+	return completions, nil
+}
+
+// fishQuote returns s as a single quoted fish string.
+func fishQuote(s string) string {
+	s = strings.ReplaceAll(s, `\`, `\\`)
+	return "'" + strings.ReplaceAll(s, "'", `\'`) + "'"
+}
+
+// wordBreaks are the characters in bash's default COMP_WORDBREAKS, other than white space and the quotes.
+const wordBreaks = "=:><;|&("
+
+// Words splits the line into words as bash does for COMP_WORDS: on white space and on the characters in
+// COMP_WORDBREAKS, a run of these becomes a word of its own, as in "--status", "=", "ok". Quoted text and
+// escaped characters are not split. If the line is empty or ends in a space, an empty word is added.
+func Words(line string) []string {
+	words := []string{}
+	word := &strings.Builder{}
+	breaks := false // word holds word break characters
+	flush := func() {
+		if word.Len() > 0 {
+			words = append(words, word.String())
+			word.Reset()
+		}
+	}
+	var quote rune
+	escaped := false
+	space := true // the line ends in white space
+	for _, r := range line {
+		space = false
+		switch {
+		case escaped:
+			escaped = false
+		case quote != 0:
+			if r == quote {
+				quote = 0
+			}
+		case r == ' ' || r == '\t' || r == '\n':
+			flush()
+			breaks, space = false, true
+			continue
+		case strings.ContainsRune(wordBreaks, r):
+			if !breaks {
+				flush()
+			}
+			breaks = true
+			word.WriteRune(r)
+			continue
+		case r == '\\':
+			escaped = true
+		case r == '\'' || r == '"':
+			quote = r
+		}
+		if breaks {
+			flush()
+			breaks = false
+		}
+		word.WriteRune(r)
+	}
+	flush()
+	if space {
+		words = append(words, "")
+	}
+	return words
+}
+
+// tempFile writes data to a new temporary file and returns its name.
+func tempFile(data []byte, pattern string) (string, error) {
+	f, err := os.CreateTemp("", pattern)
+	if err != nil {
+		return "", err
+	}
+	if _, err := f.Write(data); err != nil {
+		f.Close()
+		os.Remove(f.Name())
+		return "", err
+	}
+	if err := f.Close(); err != nil {
+		os.Remove(f.Name())
+		return "", err
+	}
+	return f.Name(), nil
+}
+
+// lines returns the non-empty lines in out, a trailing carriage return, as written by a pseudo terminal, is removed.
+func lines(out []byte) []string {
+	ls := []string{}
+	for _, l := range strings.Split(string(out), "\n") {
+		if l = strings.TrimSuffix(l, "\r"); l != "" {
+			ls = append(ls, l)
+		}
+	}
+	return ls
+}
+
+// Error is returned when the shell fails.
+type Error struct {
+	Shell  string
+	Err    error
+	Output []byte
+}
+
+func (e *Error) Error() string {
+	return e.Shell + ": " + e.Err.Error() + ": " + strings.TrimSpace(string(e.Output))
+}
+
+func (e *Error) Unwrap() error { return e.Err }
@@ -0,0 +1,27 @@
+package comptest
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestWords(t *testing.T) {
+	tests := []struct {
+		line   string
+		expect []string
+	}{
+		{"", []string{""}},
+		{"g ", []string{"g", ""}},
+		{"g vol", []string{"g", "vol"}},
+		{"g --status=", []string{"g", "--status", "="}},
+		{"g --status=ok ", []string{"g", "--status", "=", "ok", ""}},
+		{"g server:vol", []string{"g", "server", ":", "vol"}},
+		{"g a:=b", []string{"g", "a", ":=", "b"}},
+		{`g 'a:b' "c d" e\:f`, []string{"g", "'a:b'", `"c d"`, `e\:f`}},
+	}
+	for _, tc := range tests {
+		if got := Words(tc.line); !slices.Equal(got, tc.expect) {
+			t.Errorf("for %q, expected %q, got %q", tc.line, tc.expect, got)
+		}
+	}
+}
@@ -0,0 +1,88 @@
+// Package kingtest helps testing the shell completions generated by king in the real shell. Each helper skips
+// the test when the shell isn't installed.
+//
+//	parser := kong.Must(&CLI{})
+//	got := kingtest.Complete(t, "zsh", parser.Model.Node, "mytool vol")
+package kingtest
+
+import (
+	"os/exec"
+	"strings"
+	"testing"
+
+	"github.com/alecthomas/kong"
+	"github.com/miekg/king"
+	"github.com/miekg/king/internal/comptest"
+)
+
+// Complete generates the completion for the shell ("bash", "zsh" or "fish") from the node and returns the
+// completions this shell gives for line. The first word of line is used as the name of the application. Note
+// that generating the completion modifies node, so a new node should be used for each call.
+func Complete(tb testing.TB, shell string, node *kong.Node, line string) []string {
+	tb.Helper()
+	name, _, _ := strings.Cut(strings.TrimSpace(line), " ")
+	var c king.Completer
+	switch shell {
+	case "bash":
+		c = &king.Bash{}
+	case "zsh":
+		c = &king.Zsh{}
+	case "fish":
+		c = &king.Fish{}
+	default:
+		tb.Fatalf("unsupported shell: %q", shell)
+	}
+	if err := c.Completion(node, name); err != nil {
+		tb.Fatal(err)
+	}
+	switch shell {
+	case "zsh":
+		return Zsh(tb, c.Out(), line)
+	case "fish":
+		return Fish(tb, c.Out(), line)
+	}
+	return Bash(tb, c.Out(), line)
+}
+
+// Bash returns the completions bash gives for line with the bash completion script. The line is split into
+// words as bash does, the last word is the one being completed, if line ends in a space this is the empty word.
+func Bash(tb testing.TB, script []byte, line string) []string {
+	tb.Helper()
+	lookPath(tb, "bash")
+	completions, err := comptest.Bash(script, line)
+	if err != nil {
+		tb.Fatal(err)
+	}
+	return completions
+}
+
+// Zsh returns the completions zsh gives for line with the zsh completion script. The line is typed in a pseudo
+// terminal followed by a TAB, so the completions are those zsh would show.
+func Zsh(tb testing.TB, script []byte, line string) []string {
+	tb.Helper()
+	lookPath(tb, "zsh")
+	completions, err := comptest.Zsh(script, line)
+	if err != nil {
+		tb.Fatal(err)
+	}
+	return completions
+}
+
+// Fish returns the completions fish gives for line with the fish completion script, without the descriptions.
+func Fish(tb testing.TB, script []byte, line string) []string {
+	tb.Helper()
+	lookPath(tb, "fish")
+	completions, err := comptest.Fish(script, line)
+	if err != nil {
+		tb.Fatal(err)
+	}
+	return completions
+}
+
+// lookPath skips the test if shell can't be found.
+func lookPath(tb testing.TB, shell string) {
+	tb.Helper()
+	if _, err := exec.LookPath(shell); err != nil {
+		tb.Skipf("%s not found", shell)
+	}
+}
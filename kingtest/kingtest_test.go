@@ -0,0 +1,57 @@
+package kingtest
+
+import (
+	"slices"
+	"testing"
+
+	"github.com/alecthomas/kong"
+)
+
+const script = `_mytool_completions() {
+    COMPREPLY=($(compgen -W "volume version" -- "$2"))
+}
+complete -F _mytool_completions mytool
+`
+
+func TestBash(t *testing.T) {
+	tests := []struct {
+		line   string
+		expect []string
+	}{
+		{"mytool vol", []string{"volume"}},
+		{"mytool v", []string{"volume", "version"}},
+		{"mytool x", []string{}},
+	}
+	for _, tc := range tests {
+		if got := Bash(t, []byte(script), tc.line); !slices.Equal(got, tc.expect) {
+			t.Errorf("for %q, expected %v, got %v", tc.line, tc.expect, got)
+		}
+	}
+}
+
+type CLI struct {
+	Volume struct {
+		Status string `enum:"ok,rm" default:"ok" help:"Set the status."`
+	} `cmd:"" help:"Manage volumes."`
+	Version struct{} `cmd:"" help:"Show the version."`
+}
+
+func TestComplete(t *testing.T) {
+	tests := []struct {
+		line   string
+		expect []string
+	}{
+		{"mytool vol", []string{"volume"}},
+		{"mytool volume --status r", []string{"rm"}},
+	}
+	for _, shell := range []string{"bash", "zsh", "fish"} {
+		t.Run(shell, func(t *testing.T) {
+			for _, tc := range tests {
+				parser := kong.Must(&CLI{})
+				if got := Complete(t, shell, parser.Model.Node, tc.line); !slices.Equal(got, tc.expect) {
+					t.Errorf("for %q, expected %v, got %v", tc.line, tc.expect, got)
+				}
+			}
+		})
+	}
+}
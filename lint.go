@@ -0,0 +1,154 @@
+package king
+
+import (
+	"maps"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/alecthomas/kong"
+)
+
+// kongTags are the tag keys kong understands.
+var kongTags = []string{
+	"aliases", "and", "arg", "cmd", "default", "embed", "enum", "env", "envprefix", "format", "group", "help",
+	"hidden", "kong", "mapsep", "name", "negatable", "optional", "passthrough", "placeholder", "prefix",
+	"required", "sep", "set", "short", "type", "xor", "xorprefix",
+}
+
+// kingTags are the tag keys king understands.
+var kingTags = []string{"completion", "deprecated", "description", "examples", "exitcodes", "files", "seealso"}
+
+// Lint checks all commands, flags and positional arguments in the node tree starting at k and returns any
+// problems found, sorted on path, field and tag. It reports:
+//
+//   - completion tags with an unknown action, as in `completion:"<fiel>"`;
+//   - completion tags on boolean flags;
+//   - unknown tag keys that look like known ones, as in `negetable:""`;
+//   - commands, flags and positional arguments without help;
+//   - commands without a description, as these are needed for the manual page;
+//   - group keys that aren't all lowercase;
+//   - exit codes that can't be parsed;
+//   - defaults that are not one of the enum values.
+func Lint(k *kong.Node) []Problem {
+	ps := []Problem{}
+	var walk func(n *kong.Node)
+	walk = func(n *kong.Node) {
+		path := commandPath(n, " ")
+		if n.Type == kong.CommandNode {
+			if n.Help == "" {
+				ps = append(ps, Problem{Path: path, Msg: "command has no help"})
+			}
+			if !n.Hidden && n.Tag != nil && n.Tag.Get("description") == "" {
+				ps = append(ps, Problem{Path: path, Msg: "command has no description for the manual page"})
+			}
+			ps = append(ps, lintTags(n.Tag, path, "")...)
+		}
+		ps = append(ps, nodeProblems(n, path, n.Flags)...)
+		for _, f := range n.Flags {
+			field := "--" + f.Name
+			if f.Help == "" {
+				ps = append(ps, Problem{Path: path, Field: field, Msg: "flag has no help"})
+			}
+			ps = append(ps, lintValue(f.Value, path, field)...)
+		}
+		for _, p := range n.Positional {
+			field := strings.ToUpper(p.Name)
+			if p.Help == "" {
+				ps = append(ps, Problem{Path: path, Field: field, Msg: "positional argument has no help"})
+			}
+			ps = append(ps, lintValue(p, path, field)...)
+		}
+		for _, c := range n.Children {
+			if c != nil {
+				walk(c)
+			}
+		}
+	}
+	walk(k)
+	sortProblems(ps)
+	return ps
+}
+
+// lintValue checks the tags of a flag or positional argument.
+func lintValue(v *kong.Value, path, field string) []Problem {
+	ps := lintTags(v.Tag, path, field)
+	if v.Enum != "" && v.HasDefault && v.Default != "" {
+		enums := v.EnumMap()
+		defaults := []string{v.Default}
+		if v.Target.IsValid() && v.IsCumulative() {
+			defaults = strings.Split(v.Default, ",")
+		}
+		for _, d := range defaults {
+			if !enums[d] {
+				ps = append(ps, Problem{path, field, tag("default", v.Default), "default is not one of the enum values"})
+				break
+			}
+		}
+	}
+	return ps
+}
+
+// lintTags reports tag keys that are unknown, but look like a known one.
+func lintTags(t *kong.Tag, path, field string) []Problem {
+	if t == nil {
+		return nil
+	}
+	ps := []Problem{}
+	items := tagItems(t)
+	for _, key := range slices.Sorted(maps.Keys(items)) {
+		value := items[key]
+		if slices.Contains(kongTags, key) || slices.Contains(kingTags, key) {
+			continue
+		}
+		for _, known := range append(kongTags, kingTags...) {
+			if distance(key, known) <= 2 && len(known) > 3 {
+				ps = append(ps, Problem{path, field, tag(key, value), "unknown tag, did you mean " + strconv.Quote(known) + "?"})
+				break
+			}
+		}
+	}
+	return ps
+}
+
+// tagItems returns the keys and values of the tag. Kong keeps these private and only has getters for known
+// keys, so they are parsed from t.String(), which has them formatted as key:"value" in no particular order.
+func tagItems(t *kong.Tag) map[string]string {
+	items := map[string]string{}
+	s := t.String()
+	for {
+		s = strings.TrimSpace(s)
+		key, rest, ok := strings.Cut(s, ":")
+		if !ok {
+			return items
+		}
+		quoted, err := strconv.QuotedPrefix(rest)
+		if err != nil {
+			return items
+		}
+		value, _ := strconv.Unquote(quoted)
+		items[key] = value
+		s = rest[len(quoted):]
+	}
+}
+
+// distance returns the Levenshtein distance between a and b.
+func distance(a, b string) int {
+	prev := make([]int, len(b)+1)
+	cur := make([]int, len(b)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		cur[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			cur[j] = min(prev[j]+1, cur[j-1]+1, prev[j-1]+cost)
+		}
+		prev, cur = cur, prev
+	}
+	return prev[len(b)]
+}
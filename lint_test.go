@@ -0,0 +1,51 @@
+package king
+
+import (
+	"testing"
+
+	"github.com/alecthomas/kong"
+)
+
+type L struct {
+	Cmd struct {
+		File   string `help:"a file" completion:"<fiel>"`
+		Status string `help:"status" enum:"ok,rm" default:"setup"`
+		Quiet  bool   `help:"be quiet" completion:"<file>"`
+		Color  bool   `help:"color" negatible:""`
+		NoHelp string
+
+		Arg string `arg:"" help:"an arg"`
+	} `cmd:"" help:"a command" exitcodes:"0=ok,two=usage"`
+}
+
+func TestLint(t *testing.T) {
+	parser := kong.Must(&L{}, kong.Name("l"))
+	ps := Lint(parser.Model.Node)
+	tests := []Problem{
+		{"l cmd", "", "", "command has no description for the manual page"},
+		{"l cmd", "", `exitcodes:"0=ok,two=usage"`, "exit codes must be written as code=meaning, with code in 0-255"},
+		{"l cmd", "--color", `negatible:""`, `unknown tag, did you mean "negatable"?`},
+		{"l cmd", "--file", `completion:"<fiel>"`, "unknown action"},
+		{"l cmd", "--no-help", "", "flag has no help"},
+		{"l cmd", "--quiet", `completion:"<file>"`, "a boolean flag can not have completion"},
+		{"l cmd", "--status", `default:"setup"`, "default is not one of the enum values"},
+	}
+	if len(ps) != len(tests) {
+		t.Fatalf("expected %d problems, got %d: %v", len(tests), len(ps), ps)
+	}
+	for i := range tests {
+		if ps[i] != tests[i] {
+			t.Errorf("test %d, expected problem %q, got %q", i, tests[i], ps[i])
+		}
+	}
+}
+
+func TestLintTypo(t *testing.T) {
+	parser := kong.Must(&T{})
+	for _, p := range Lint(parser.Model.Node) {
+		if p.Tag == `negetable:""` {
+			return
+		}
+	}
+	t.Errorf("expected negetable typo to be found")
+}
@@ -4,7 +4,6 @@ import (
 	"bytes"
 	"fmt"
 	"io"
-	"log"
 	"maps"
 	"os"
 	"slices"
@@ -22,13 +21,25 @@ import (
 
 // Man is a manual page generator.
 type Man struct {
-	name      string
-	manual    []byte
-	Section   int // See mmark's documentation
-	Area      string
-	WorkGroup string
-	Template  string       // If empty [ManTemplate] is used.
-	Flags     []*kong.Flag // Any global flags that the should Application Node have. There are documented after the normal flags.
+	name        string
+	manual      []byte
+	node        *kong.Node        // The node the manual is generated for.
+	title       string            // Title of the manual, as in "c volume list".
+	seealso     []string          // Names of the parent and subcommand pages to cross reference.
+	links       map[string]string // Set by Tree, links subcommands to the names of their pages.
+	Section     int               // See mmark's documentation
+	Area        string
+	WorkGroup   string
+	Template    string               // If empty [ManTemplate] is used.
+	CSS         string               // URL of a stylesheet used in the HTML output, see [Man.WriteHTML].
+	FrontMatter string               // Template for the front matter of the markdown output, see [Man.WriteMarkdown].
+	Examples    map[string][]Example // Extra examples keyed by command path, as in "volume list", shown after the ones from the examples tag.
+	ExitCodes   []ExitCode           // Exit codes of all commands, the exitcodes tags of a command and its parents override these.
+	Files       []File               // Files used by all commands, shown after the ones from the files tag.
+	SeeAlso     []string             // Extra references for all pages, as in "ls(1)", shown after the ones from the seealso tag.
+	Authors     []string             // The authors, as in "Miek Gieben <miek@miek.nl>".
+	Bugs        string               // Text for the Bugs section, as in "Report bugs at https://github.com/miekg/king/issues.".
+	Flags       []*kong.Flag         // Any global flags that the should Application Node have. There are documented after the normal flags.
 }
 
 // ManTemplate is the default manual page template used when generating a manual page. Where each function
@@ -44,6 +55,15 @@ type Man struct {
 //   - arguments: a rundown of each of the arguments this command has.
 //   - options: a list documenting each of the options.
 //   - globals: any global flags, from m.Flags.
+//   - exitstatus: the exit codes of the command, from the "exitcodes" tags of the command and its parents and
+//     from m.ExitCodes.
+//   - environment: the environment variables used by the flags of the command, its parents and m.Flags.
+//   - files: the files from the command's "files" tag and from m.Files.
+//   - examples: the examples from the command's "examples" tags and from m.Examples.
+//   - authors: the authors from m.Authors.
+//   - bugs: the text from m.Bugs.
+//   - seealso: references to the parent and subcommand pages, followed by the ones from the command's
+//     "seealso" tag and from m.SeeAlso.
 //
 // Note that the TOML manual header is always used.
 const ManTemplate = `{{name -}}
@@ -59,6 +79,20 @@ const ManTemplate = `{{name -}}
 {{options -}}
 
 {{globals -}}
+
+{{exitstatus -}}
+
+{{environment -}}
+
+{{files -}}
+
+{{examples -}}
+
+{{authors -}}
+
+{{bugs -}}
+
+{{seealso -}}
 `
 
 // Out returns the manual in markdown form.
@@ -69,12 +103,7 @@ func (m *Man) Write(w ...io.Writer) error {
 	if m.manual == nil {
 		return fmt.Errorf("no manual")
 	}
-	p := parser.NewWithExtensions(parser.FencedCode | parser.DefinitionLists | parser.Tables)
-	p.Opts = parser.Options{
-		ParserHook: func(data []byte) (ast.Node, []byte, int) { return mparser.Hook(data) },
-		Flags:      parser.FlagsNone,
-	}
-	doc := markdown.Parse(m.manual, p)
+	doc := m.parse()
 	renderer := man.NewRenderer(man.RendererOptions{})
 	md := markdown.Render(doc, renderer)
 	if len(w) > 0 {
@@ -84,6 +113,16 @@ func (m *Man) Write(w ...io.Writer) error {
 	return os.WriteFile(fmt.Sprintf("%s.%d", m.name, m.Section), md, 0644)
 }
 
+// parse parses the markdown of the manual page.
+func (m *Man) parse() ast.Node {
+	p := parser.NewWithExtensions(parser.FencedCode | parser.DefinitionLists | parser.Tables)
+	p.Opts = parser.Options{
+		ParserHook: func(data []byte) (ast.Node, []byte, int) { return mparser.Hook(data) },
+		Flags:      parser.FlagsNone,
+	}
+	return markdown.Parse(m.manual, p)
+}
+
 // Manual generates a manual page for child node that can be found via field, where field may contain
 // a space seperated list of node names: "mfa list", looks for the mfa node and its child named list.
 // On the node k the following tags are used:
@@ -113,16 +152,23 @@ func (m *Man) Write(w ...io.Writer) error {
 // and then call Manual with: m.Manual(parser.Model.Node, "_wrap", "MyExec", "")
 // The prevent "wrap" showing up as a valid command, is can be prefixed it with _. This only checked on the
 // first element in path.
-func (m *Man) Manual(k *kong.Node, path, altname, rootname string) {
+//
+// If the command can't be found or has problems an *[Error] is returned, template errors are returned as is.
+func (m *Man) Manual(k *kong.Node, path, altname, rootname string) error {
 	fields := strings.Fields(path)
-	if strings.HasPrefix(fields[0], "_") {
+	if len(fields) > 0 && strings.HasPrefix(fields[0], "_") {
 		fields[0] = fields[0][1:]
 	}
+	cmdpath := strings.TrimSpace(rootname + " " + strings.Join(fields, " "))
 	cmd := nodePath(k, fields)
 	if cmd == nil {
-		return
+		return problems([]Problem{{Path: cmdpath, Msg: "command not found"}})
+	}
+	if err := checkManual(cmd, cmdpath); err != nil {
+		return err
 	}
 	m.name = altname
+	m.seealso = pageRefs(cmd, fields, altname, rootname, strings.HasPrefix(path, "_"))
 
 	funcMap := template.FuncMap{
 		"name":        func() string { return name(cmd, altname, rootname) },
@@ -132,6 +178,13 @@ func (m *Man) Manual(k *kong.Node, path, altname, rootname string) {
 		"commands":    func() string { return commands(cmd) },
 		"options":     func() string { return options(cmd) },
 		"globals":     func() string { return globals(m.Flags) },
+		"exitstatus":  func() string { return exitstatus(cmd, m.ExitCodes) },
+		"environment": func() string { return environment(cmd, m.Flags) },
+		"files":       func() string { return filesection(cmd, m.Files) },
+		"examples":    func() string { return m.examples(cmd, fields) },
+		"authors":     func() string { return authors(m.Authors) },
+		"bugs":        func() string { return bugs(m.Bugs) },
+		"seealso":     func() string { return seealso(m.seealso, append(seeAlso(cmd), m.SeeAlso...), m.Section) },
 	}
 
 	if m.Template == "" {
@@ -144,8 +197,7 @@ func (m *Man) Manual(k *kong.Node, path, altname, rootname string) {
 	tmpl = template.New("manualpage").Funcs(funcMap)
 	tmpl, err = tmpl.Parse(m.Template)
 	if err != nil {
-		log.Printf("Failed to generate manual page: %s", err)
-		return
+		return err
 	}
 
 	format := `%%%%%%
@@ -161,26 +213,34 @@ workgroup = "%s"
 	if name == "" {
 		name = fmt.Sprintf("%s %s", rootname, path)
 	}
+	m.node, m.title = cmd, name
 	fmt.Fprintf(b, format, name, m.Section, m.Area, m.WorkGroup)
 	if err = tmpl.Execute(b, nil); err != nil {
-		log.Printf("Failed to generate manual page: %s", err)
-		return
+		return err
 	}
 	m.manual = b.Bytes()
+	return nil
 }
 
 // name implements the template func name.
 func name(cmd *kong.Node, altname, rootname string) string {
-	help := strings.TrimSuffix(cmd.Help, ".")
-	if strings.ToUpper(help) != help && len(help) > 2 { // not all caps
-		help = strings.ToLower(help[0:1]) + help[1:]
-	}
+	help := clause(cmd.Help)
 	if altname == "" {
 		altname = rootname + " " + commandName(cmd)
 	}
 	return fmt.Sprintf("## Name\n\n%s - %s\n\n", altname, help)
 }
 
+// clause returns help so that it can be used in a sentence: the final dot is removed and the first letter is
+// lowercased if the help isn't all caps.
+func clause(help string) string {
+	help = strings.TrimSuffix(help, ".")
+	if strings.ToUpper(help) != help && len(help) > 2 { // not all caps
+		help = strings.ToLower(help[0:1]) + help[1:]
+	}
+	return help
+}
+
 func (m *Man) synopsis(cmd *kong.Node, path, altname, rootname string) string {
 	s := &strings.Builder{}
 
@@ -203,6 +263,9 @@ func (m *Man) synopsis(cmd *kong.Node, path, altname, rootname string) string {
 		} else {
 			argstring += " *[" + strings.ToUpper(name) + "]*"
 		}
+		if variadic(a) {
+			argstring += "..."
+		}
 	}
 	for _, f := range cmd.Flags {
 		if f.Hidden {
@@ -249,7 +312,10 @@ func (m *Man) synopsis(cmd *kong.Node, path, altname, rootname string) string {
 		path += " "
 	}
 	fmt.Fprintf(s, "## Synopsis\n\n")
-	if altname != "" {
+	if cmd.Parent == nil { // root node, only altname makes sense
+		ignore = true
+	}
+	if altname != "" && (ignore || altname != rootname+path+commandName(cmd)) {
 		fmt.Fprintf(s, "`%s`%s%s%s\n\n", altname, optstring, argstring, cmdstring)
 	}
 	if !ignore {
@@ -325,12 +391,7 @@ func options(cmd *kong.Node) string {
 			}
 		}
 		fmt.Fprintln(s)
-		// format groups options
-		for k := range groups {
-			if k != strings.ToLower(k) {
-				log.Fatalf("Group keys must be all lowercase: %s", k)
-			}
-		}
+		// format groups options, the keys are checked to be lowercase in checkManual
 		keys := slices.Sorted(maps.Keys(groups))
 
 		for _, group := range keys {
@@ -353,3 +414,162 @@ func globals(flags []*kong.Flag) string {
 	}
 	return s.String()
 }
+
+// exitstatus implements the exitstatus func name.
+func exitstatus(cmd *kong.Node, base []ExitCode) string {
+	codes := map[int]string{}
+	for _, c := range base {
+		codes[c.Code] = c.Meaning
+	}
+	nodes := []*kong.Node{}
+	for n := cmd; n != nil; n = n.Parent {
+		nodes = append(nodes, n)
+	}
+	for _, n := range slices.Backward(nodes) { // root first, so commands override their parents
+		exs, _ := exitCodes(n)
+		for _, c := range exs {
+			codes[c.Code] = c.Meaning
+		}
+	}
+	if len(codes) == 0 {
+		return ""
+	}
+
+	s := &strings.Builder{}
+	fmt.Fprintf(s, "## Exit Status\n\n")
+	for _, code := range slices.Sorted(maps.Keys(codes)) {
+		fmt.Fprintf(s, "`%d`\n:   %s\n\n", code, codes[code])
+	}
+	return s.String()
+}
+
+// environment implements the environment func name.
+func environment(cmd *kong.Node, globals []*kong.Flag) string {
+	flags := map[string][]*kong.Flag{}
+	add := func(fs []*kong.Flag) {
+		for _, f := range fs {
+			if f.Hidden {
+				continue
+			}
+			for _, env := range flagEnvs(f) {
+				if !slices.Contains(flags[env], f) {
+					flags[env] = append(flags[env], f)
+				}
+			}
+		}
+	}
+	for n := cmd; n != nil; n = n.Parent {
+		add(n.Flags)
+	}
+	add(globals)
+	if len(flags) == 0 {
+		return ""
+	}
+
+	s := &strings.Builder{}
+	fmt.Fprintf(s, "## Environment\n\n")
+	for _, env := range slices.Sorted(maps.Keys(flags)) {
+		fmt.Fprintf(s, "`%s`\n", env)
+		for i, f := range flags[env] {
+			if i == 0 {
+				fmt.Fprintf(s, ":   ")
+			} else {
+				fmt.Fprintf(s, " ")
+			}
+			fmt.Fprintf(s, "Sets **--%s**: %s", f.Name, clause(f.Help))
+			if f.Default != "" {
+				fmt.Fprintf(s, ", %q when not set", f.Default)
+			}
+			fmt.Fprint(s, ".")
+		}
+		fmt.Fprint(s, "\n\n")
+	}
+	return s.String()
+}
+
+// filesection implements the files func name.
+func filesection(cmd *kong.Node, global []File) string {
+	fs := append(files(cmd), global...)
+	if len(fs) == 0 {
+		return ""
+	}
+	s := &strings.Builder{}
+	fmt.Fprintf(s, "## Files\n\n")
+	for _, f := range fs {
+		fmt.Fprintf(s, "*%s*\n:   %s\n\n", f.Path, f.Description)
+	}
+	return s.String()
+}
+
+func (m *Man) examples(cmd *kong.Node, path []string) string {
+	exs := append(examples(cmd), m.Examples[strings.Join(path, " ")]...)
+	if len(exs) == 0 {
+		return ""
+	}
+	s := &strings.Builder{}
+	fmt.Fprintf(s, "## Examples\n\n")
+	for _, e := range exs {
+		if e.Explanation != "" {
+			fmt.Fprintf(s, "%s\n\n", e.Explanation)
+		}
+		fmt.Fprintf(s, "```\n%s\n```\n\n", strings.TrimSpace(e.Command))
+	}
+	return s.String()
+}
+
+// seealso implements the seealso func name. The pages in refs are referenced in section, extra references are used
+// as is, unless they lack a section, as in "kong", then section is used.
+func seealso(refs, extra []string, section int) string {
+	if len(refs) == 0 && len(extra) == 0 {
+		return ""
+	}
+	all := []string{}
+	for _, ref := range refs {
+		all = append(all, fmt.Sprintf("*%s*(%d)", ref, section))
+	}
+	for _, ref := range extra {
+		name, sect, ok := strings.Cut(ref, "(")
+		if !ok {
+			sect = fmt.Sprintf("%d)", section)
+		}
+		all = append(all, fmt.Sprintf("*%s*(%s", name, sect))
+	}
+	return fmt.Sprintf("## See Also\n\n%s\n", strings.Join(all, ", "))
+}
+
+// authors implements the authors func name.
+func authors(names []string) string {
+	if len(names) == 0 {
+		return ""
+	}
+	return fmt.Sprintf("## Authors\n\n%s\n\n", strings.Join(names, ", "))
+}
+
+// bugs implements the bugs func name.
+func bugs(text string) string {
+	if text == "" {
+		return ""
+	}
+	return fmt.Sprintf("## Bugs\n\n%s\n\n", text)
+}
+
+// pageRefs returns the names of the pages of the parent and the subcommands of cmd. The pages are named as
+// [Man.Tree] does, the command path with the words joined with a dash.
+func pageRefs(cmd *kong.Node, path []string, altname, rootname string, ignore bool) []string {
+	refs := []string{}
+	if cmd.Parent != nil && !(ignore && len(path) == 1) {
+		if parent := strings.Join(strings.Fields(rootname+" "+strings.Join(path[:len(path)-1], " ")), "-"); parent != "" {
+			refs = append(refs, parent)
+		}
+	}
+	page := strings.Join(strings.Fields(rootname+" "+strings.Join(path, " ")), "-")
+	if altname != "" {
+		page = strings.Join(strings.Fields(altname), "-")
+	}
+	for _, c := range cmd.Children {
+		if c.Type == kong.CommandNode && !c.Hidden {
+			refs = append(refs, page+"-"+c.Name)
+		}
+	}
+	return refs
+}
@@ -56,15 +56,18 @@ func formatFlag(s io.Writer, f *kong.Flag, quote ...bool) {
 	}
 
 	fmt.Fprintln(s)
-	deprecated := ""
-	if f.Tag.Has("deprecated") {
-		deprecated = "(Deprecated) "
+	dep := ""
+	if msg, ok := deprecated(f); ok {
+		dep = "(Deprecated) "
+		if msg != "" {
+			dep = fmt.Sprintf("(Deprecated: %s) ", msg)
+		}
 	}
-	fmt.Fprintf(s, "%s:   %s%s", q, deprecated, f.Help)
+	fmt.Fprintf(s, "%s:   %s%s", q, dep, f.Help)
 	if f.Required {
 		fmt.Fprintf(s, " This is a required option.")
 	}
-	if f.Tag.Get("type") == "counter" {
+	if repeatable(f.Value) {
 		fmt.Fprintf(s, " This option can be repeated.")
 	}
 	if f.Format != "" {
@@ -102,14 +105,15 @@ func formatFlag(s io.Writer, f *kong.Flag, quote ...bool) {
 	}
 
 	if f.Envs != nil {
+		envs := make([]string, len(f.Envs))
 		for i := range f.Envs {
-			f.Envs[i] = "`${" + f.Envs[i] + "}`"
+			envs[i] = "`${" + f.Envs[i] + "}`"
 		}
 		vars := "variables"
-		if len(f.Envs) == 1 {
+		if len(envs) == 1 {
 			vars = "variable"
 		}
-		fmt.Fprintf(s, " The default value is derived from the environment %s: %s.", vars, strings.Join(f.Envs, ", "))
+		fmt.Fprintf(s, " The default value is derived from the environment %s: %s.", vars, strings.Join(envs, ", "))
 	}
 
 	if f.Xor != nil {
@@ -154,6 +158,9 @@ func formatArg(s io.Writer, p *kong.Positional) {
 	if !p.Required {
 		fmt.Fprintf(s, " This argument is optional.")
 	}
+	if variadic(p) {
+		fmt.Fprintf(s, " This argument can be repeated.")
+	}
 	if p.Enum != "" {
 		fmt.Fprintf(s, " Valid values are: ")
 		enums := p.EnumSlice()
@@ -0,0 +1,155 @@
+package king
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/alecthomas/kong"
+	"github.com/gomarkdown/markdown"
+	"github.com/gomarkdown/markdown/ast"
+	"github.com/gomarkdown/markdown/html"
+	"github.com/mmarkdown/mmark/v2/render/mhtml"
+)
+
+// WriteHTML writes the manual page as a complete HTML page to the file m.name.html. If the optional writer is
+// given it is written to that. Each option, argument, subcommand, environment variable and exit code gets an
+// anchor: "option-<name>", "argument-<name>", "command-<name>", "env-<name>" and "exit-<code>". If m.CSS is set it is used as the stylesheet for the page. When
+// generated with [Man.Tree] the subcommands link to their pages.
+func (m *Man) WriteHTML(w ...io.Writer) error {
+	if m.manual == nil {
+		return fmt.Errorf("no manual")
+	}
+	doc := m.parse()
+	mhtmlOpts := mhtml.RendererOptions{}
+	section := "" // the section being rendered, as in "Environment"
+	opts := html.RendererOptions{
+		Title: m.name,
+		CSS:   m.CSS,
+		Flags: html.CompletePage, // no smartypants, it mangles options: "--stat" becomes "&ndash;stat"
+		RenderNodeHook: func(w io.Writer, node ast.Node, entering bool) (ast.WalkStatus, bool) {
+			if heading, ok := node.(*ast.Heading); ok && entering && heading.Level == 2 {
+				section = headingText(heading)
+			}
+			if status, ok := m.htmlHook(w, node, entering, section); ok {
+				return status, ok
+			}
+			return mhtmlOpts.RenderHook(w, node, entering)
+		},
+		Generator: `  <meta name="GENERATOR" content="github.com/miekg/king`,
+	}
+	page := markdown.Render(doc, html.NewRenderer(opts))
+	if len(w) > 0 {
+		w[0].Write(page)
+		return nil
+	}
+	return os.WriteFile(m.name+".html", page, 0644)
+}
+
+// htmlHook adds anchors to the terms of the definition lists in section, and links subcommands to their pages.
+func (m *Man) htmlHook(w io.Writer, node ast.Node, entering bool, section string) (ast.WalkStatus, bool) {
+	item, ok := node.(*ast.ListItem)
+	if !ok || item.ListFlags&ast.ListTypeTerm == 0 {
+		return ast.GoToNext, false
+	}
+	term := termName(item)
+	if term == "" {
+		return ast.GoToNext, false
+	}
+	link := m.links[term]
+	if !entering {
+		if link != "" {
+			io.WriteString(w, "</a>")
+		}
+		io.WriteString(w, "</dt>\n")
+		return ast.GoToNext, true
+	}
+
+	fmt.Fprintf(w, "\n<dt id=%q>", m.anchor(term, section))
+	if link != "" {
+		fmt.Fprintf(w, "<a href=%q>", link+".html")
+	}
+	return ast.GoToNext, true
+}
+
+// anchor returns the id of the term in section. Environment variables and exit codes have a section of their
+// own, the other terms are told apart with the node the page is generated for.
+func (m *Man) anchor(term, section string) string {
+	switch {
+	case section == "Environment":
+		return "env-" + term
+	case section == "Exit Status":
+		return "exit-" + term
+	case strings.HasPrefix(term, "--"):
+		return "option-" + strings.TrimPrefix(strings.TrimPrefix(term, "--"), "[no-]")
+	}
+	for _, c := range m.node.Children {
+		if c.Type == kong.CommandNode && (c.Name == term || c.Tag.Get("cmd") == term) {
+			return "command-" + term
+		}
+	}
+	return "argument-" + strings.ToLower(term)
+}
+
+// headingText returns the text of the heading.
+func headingText(heading *ast.Heading) string {
+	s := &strings.Builder{}
+	ast.WalkFunc(heading, func(node ast.Node, entering bool) ast.WalkStatus {
+		if text, ok := node.(*ast.Text); ok && entering {
+			s.Write(text.Literal)
+		}
+		return ast.GoToNext
+	})
+	return s.String()
+}
+
+// termName returns the literal of the first code span in the term of a definition list.
+func termName(item *ast.ListItem) (name string) {
+	ast.WalkFunc(item, func(node ast.Node, entering bool) ast.WalkStatus {
+		if code, ok := node.(*ast.Code); ok && entering {
+			name = string(code.Literal)
+			return ast.Terminate
+		}
+		return ast.GoToNext
+	})
+	return name
+}
+
+// WriteHTML writes all pages as HTML to the directory dir, each to the file name.html. An index.html is
+// written that links to all pages.
+func (p Pages) WriteHTML(dir string) error {
+	index := &strings.Builder{}
+	for _, m := range p {
+		f, err := os.Create(filepath.Join(dir, m.name+".html"))
+		if err != nil {
+			return err
+		}
+		if err := m.WriteHTML(f); err != nil {
+			f.Close()
+			return err
+		}
+		if err := f.Close(); err != nil {
+			return err
+		}
+		fmt.Fprintf(index, "  <li><a href=%q>%s</a></li>\n", m.name+".html", m.name)
+	}
+	css := ""
+	if len(p) > 0 && p[0].CSS != "" {
+		css = fmt.Sprintf("  <link rel=\"stylesheet\" type=\"text/css\" href=%q>\n", p[0].CSS)
+	}
+	format := `<!DOCTYPE html>
+<html>
+<head>
+  <meta charset="utf-8">
+  <title>Index</title>
+%s</head>
+<body>
+<ul>
+%s</ul>
+</body>
+</html>
+`
+	return os.WriteFile(filepath.Join(dir, "index.html"), fmt.Appendf(nil, format, css, index.String()), 0644)
+}
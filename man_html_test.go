@@ -0,0 +1,79 @@
+package king
+
+import (
+	"bytes"
+	"maps"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/alecthomas/kong"
+)
+
+func TestManHTML(t *testing.T) {
+	parser := kong.Must(&T{}, kong.Description("The c command."))
+	m := &Man{Section: 1, CSS: "man.css"}
+	pages, err := m.Tree(parser.Model.Node, "c")
+	if err != nil {
+		t.Fatal(err)
+	}
+	buf := &bytes.Buffer{}
+	if err := pages[3].WriteHTML(buf); err != nil {
+		t.Fatal(err)
+	}
+	tests := []string{
+		`<link rel="stylesheet" type="text/css" href="man.css"`,
+		`<dt id="option-status">`,
+		`<dt id="command-do-even-more"><a href="c-even-more-do-even-more.html">`,
+	}
+	for i := range tests {
+		if !strings.Contains(buf.String(), tests[i]) {
+			t.Errorf("test %d, expected %q to be present, but did not find it", i, tests[i])
+		}
+	}
+
+	dir := t.TempDir()
+	if err := pages.WriteHTML(dir); err != nil {
+		t.Fatal(err)
+	}
+	index, err := os.ReadFile(filepath.Join(dir, "index.html"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Contains(index, []byte(`<a href="c-even-more.html">c-even-more</a>`)) {
+		t.Errorf("expected index to link to c-even-more.html, got %s", index)
+	}
+}
+
+func TestManHTMLAnchors(t *testing.T) {
+	parser := kong.Must(&G{})
+	m := &Man{Section: 1}
+	pages, err := m.Tree(parser.Model.Node, "g")
+	if err != nil {
+		t.Fatal(err)
+	}
+	id := regexp.MustCompile(`<dt id="([^"]*)">`)
+	for _, page := range pages {
+		buf := &bytes.Buffer{}
+		if err := page.WriteHTML(buf); err != nil {
+			t.Fatal(err)
+		}
+		seen := map[string]bool{}
+		for _, match := range id.FindAllStringSubmatch(buf.String(), -1) {
+			if seen[match[1]] {
+				t.Errorf("page %s, id %q is not unique", page.name, match[1])
+			}
+			seen[match[1]] = true
+		}
+		if page.name == "g-volume" {
+			for _, expect := range []string{"command-list", "env-G_CONFIG", "exit-0", "exit-3"} {
+				if !seen[expect] {
+					t.Errorf("page %s, expected id %q, got %v", page.name, expect, slices.Sorted(maps.Keys(seen)))
+				}
+			}
+		}
+	}
+}
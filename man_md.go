@@ -0,0 +1,133 @@
+package king
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"text/template"
+)
+
+// FrontMatterData is the data the [Man] FrontMatter template is executed with.
+type FrontMatterData struct {
+	Name    string // Name of the page, as in "c-volume-list".
+	Title   string // The command, as in "c volume list".
+	Help    string // The help of the command.
+	Section int
+}
+
+// WriteMarkdown writes the manual page as CommonMark to the file m.name.md, for use on documentation sites. If
+// the optional writer is given it is written to that. The mmark title block is left out, and if m.FrontMatter
+// is set it is executed and put at the start, for Hugo this could be:
+//
+//	---
+//	title: "{{.Title}}"
+//	---
+//
+// Definition lists are converted to lists and when generated with [Man.Tree] the subcommands and the See Also
+// section link to their pages.
+func (m *Man) WriteMarkdown(w ...io.Writer) error {
+	if m.manual == nil {
+		return fmt.Errorf("no manual")
+	}
+	b := &bytes.Buffer{}
+	if err := m.frontMatter(b, FrontMatterData{m.name, m.title, m.node.Help, m.Section}); err != nil {
+		return err
+	}
+	b.Write(m.markdown())
+	if len(w) > 0 {
+		w[0].Write(b.Bytes())
+		return nil
+	}
+	return os.WriteFile(m.name+".md", b.Bytes(), 0644)
+}
+
+func (m *Man) frontMatter(w io.Writer, data FrontMatterData) error {
+	if m.FrontMatter == "" {
+		return nil
+	}
+	tmpl, err := template.New("frontmatter").Parse(m.FrontMatter)
+	if err != nil {
+		return err
+	}
+	if err := tmpl.Execute(w, data); err != nil {
+		return err
+	}
+	fmt.Fprintln(w)
+	return nil
+}
+
+var codeSpan = regexp.MustCompile("`([^`]+)`")
+
+// markdown returns the manual without the title block, with the definition lists converted to lists and with
+// links to the pages of subcommands.
+func (m *Man) markdown() []byte {
+	md := string(m.manual)
+	if strings.HasPrefix(md, "%%%\n") {
+		if i := strings.Index(md[4:], "%%%\n"); i >= 0 {
+			md = strings.TrimLeft(md[4+i+4:], "\n")
+		}
+	}
+
+	s := &strings.Builder{}
+	lines := strings.Split(md, "\n")
+	for i := 0; i < len(lines); i++ {
+		line := lines[i]
+		q := ""
+		if strings.HasPrefix(line, "> ") {
+			q = "> "
+		}
+		if i+1 >= len(lines) || !strings.HasPrefix(lines[i+1], q+":   ") {
+			fmt.Fprintln(s, line)
+			continue
+		}
+		// definition list: term followed by ":   definition"
+		term := strings.TrimPrefix(line, q)
+		if match := codeSpan.FindStringSubmatch(term); match != nil {
+			if link, ok := m.links[match[1]]; ok {
+				term = fmt.Sprintf("[%s](%s.md)", term, link)
+			}
+		}
+		fmt.Fprintf(s, "%s- %s  \n", q, term)
+		fmt.Fprintf(s, "%s  %s\n", q, strings.TrimPrefix(lines[i+1], q+":   "))
+		i++
+	}
+
+	out := strings.TrimSuffix(s.String(), "\n")
+	for _, ref := range m.seealso {
+		out = strings.ReplaceAll(out, fmt.Sprintf("*%s*(%d)", ref, m.Section), fmt.Sprintf("[%s](%s.md)", ref, ref))
+	}
+	return []byte(out)
+}
+
+// WriteMarkdown writes all pages as CommonMark to the directory dir, each to the file name.md. An index.md
+// is written that lists all pages.
+func (p Pages) WriteMarkdown(dir string) error {
+	index := &strings.Builder{}
+	for _, m := range p {
+		f, err := os.Create(filepath.Join(dir, m.name+".md"))
+		if err != nil {
+			return err
+		}
+		if err := m.WriteMarkdown(f); err != nil {
+			f.Close()
+			return err
+		}
+		if err := f.Close(); err != nil {
+			return err
+		}
+		depth := len(strings.Fields(m.title)) - 1
+		fmt.Fprintf(index, "%s- [%s](%s.md) - %s\n", strings.Repeat("  ", depth), m.title, m.name, m.node.Help)
+	}
+	b := &bytes.Buffer{}
+	if len(p) > 0 {
+		if err := p[0].frontMatter(b, FrontMatterData{Name: "index", Title: "Index", Section: p[0].Section}); err != nil {
+			return err
+		}
+	}
+	fmt.Fprintf(b, "# Index\n\n%s", index.String())
+	return os.WriteFile(filepath.Join(dir, "index.md"), b.Bytes(), 0644)
+}
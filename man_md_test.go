@@ -0,0 +1,50 @@
+package king
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/alecthomas/kong"
+)
+
+func TestManMarkdown(t *testing.T) {
+	parser := kong.Must(&T{}, kong.Description("The c command."))
+	m := &Man{Section: 1, FrontMatter: "---\ntitle: \"{{.Title}}\"\n---"}
+	pages, err := m.Tree(parser.Model.Node, "c")
+	if err != nil {
+		t.Fatal(err)
+	}
+	buf := &bytes.Buffer{}
+	if err := pages[3].WriteMarkdown(buf); err != nil {
+		t.Fatal(err)
+	}
+	out := buf.String()
+	if !strings.HasPrefix(out, "---\ntitle: \"c even-more\"\n---\n## Name\n") {
+		t.Errorf("expected front matter and no title block, got %s", out)
+	}
+	tests := []string{
+		"- [`do-even-more`](c-even-more-do-even-more.md)  \n  do it agian, but more.\n",
+		"- `--status`, `-s` *STATUS*  \n  Set the status",
+		"[c](c.md), [c-even-more-do-even-more](c-even-more-do-even-more.md)",
+	}
+	for i := range tests {
+		if !strings.Contains(out, tests[i]) {
+			t.Errorf("test %d, expected %q to be present, but did not find it", i, tests[i])
+		}
+	}
+
+	dir := t.TempDir()
+	if err := pages.WriteMarkdown(dir); err != nil {
+		t.Fatal(err)
+	}
+	index, err := os.ReadFile(filepath.Join(dir, "index.md"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Contains(index, []byte("\n    - [c even-more do-even-more](c-even-more-do-even-more.md) - do it agian, but more.\n")) {
+		t.Errorf("expected index to list c-even-more-do-even-more, got %s", index)
+	}
+}
@@ -1,7 +1,6 @@
 package king
 
 import (
-	"fmt"
 	"strings"
 	"testing"
 
@@ -13,25 +12,47 @@ var manf = &kong.Flag{Value: &kong.Value{Name: "man", Help: "how context-sensiti
 func TestMan(t *testing.T) {
 	parser := kong.Must(&T{})
 	m := &Man{Flags: []*kong.Flag{manf}, Section: 1, Area: "User Commands", WorkGroup: "The hard working team"}
-	m.Manual(parser.Model.Node, "even-more do-even-more", "ListEvenMore", "c")
-	println(string(m.Out()))
-	m.Write()
+	if err := m.Manual(parser.Model.Node, "even-more do-even-more", "ListEvenMore", "c"); err != nil {
+		t.Fatal(err)
+	}
+	golden(t, "man/ListEvenMore.md", m.Out())
 }
 
 func TestManNoAlias(t *testing.T) {
 	parser := kong.Must(&T{})
 	m := &Man{Flags: []*kong.Flag{manf}, Section: 1, Area: "User Commands", WorkGroup: "The hard working team"}
-	m.Manual(parser.Model.Node, "even-more do-even-more", "", "c")
-	println(string(m.Out()))
-	m.Write()
+	if err := m.Manual(parser.Model.Node, "even-more do-even-more", "", "c"); err != nil {
+		t.Fatal(err)
+	}
+	golden(t, "man/c-even-more-do-even-more.md", m.Out())
 }
 
 func TestManNoSuchNode(t *testing.T) {
 	parser := kong.Must(&T{})
 	m := &Man{Section: 1, Area: "User Commands", WorkGroup: "The hard working team"}
-	fmt.Printf("%+v\n", parser.Model.Node)
-	fmt.Printf("%+v\n", parser.Model.Tag.Get("description"))
-	m.Manual(parser.Model.Node, "does-not-exist", "", "c")
+	if err := m.Manual(parser.Model.Node, "does-not-exist", "", "c"); err == nil {
+		t.Errorf("expected error for non existing node")
+	}
+}
+
+type GroupT struct {
+	Cmd struct {
+		A string `help:"a" group:"Upper"`
+		B string `help:"b" group:"Upper"`
+	} `cmd:"" help:"cmd"`
+}
+
+func TestManGroupKey(t *testing.T) {
+	parser := kong.Must(&GroupT{})
+	m := &Man{Section: 1}
+	err := m.Manual(parser.Model.Node, "cmd", "", "c")
+	kerr, ok := err.(*Error)
+	if !ok {
+		t.Fatalf("expected *Error, got %v", err)
+	}
+	if len(kerr.Problems) != 2 {
+		t.Errorf("expected 2 problems, got %d: %s", len(kerr.Problems), kerr)
+	}
 }
 
 type WrapT struct {
@@ -41,8 +62,10 @@ type WrapT struct {
 func TestManMain(t *testing.T) {
 	parser := kong.Must(&WrapT{})
 	m := &Man{Section: 1, Area: "User Commands", WorkGroup: "The hard working team"}
-	m.Manual(parser.Model.Node, "_wrap", "MyExec", "")
-	println(string(m.Out()))
+	if err := m.Manual(parser.Model.Node, "_wrap", "MyExec", ""); err != nil {
+		t.Fatal(err)
+	}
+	golden(t, "man/MyExec.md", m.Out())
 }
 
 func TestManHelp(t *testing.T) {
@@ -66,3 +89,132 @@ workgroup = "B"
 ## Name
 
 MyExec - my help`
+
+type ExampleT struct {
+	List struct{} `cmd:"" help:"List volumes." examples:"c list # List all volumes." examples:"c list | wc -l"`
+}
+
+func TestManExamples(t *testing.T) {
+	parser := kong.Must(&ExampleT{})
+	m := &Man{Section: 1, Template: `{{examples}}`, Examples: map[string][]Example{
+		"list": {{Command: "c list |\n  grep ok", Explanation: "Show the ok volumes."}},
+	}}
+	if err := m.Manual(parser.Model.Node, "list", "", "c"); err != nil {
+		t.Fatal(err)
+	}
+	const expect = "## Examples\n\nList all volumes.\n\n```\nc list\n```\n\n```\nc list | wc -l\n```\n\n" +
+		"Show the ok volumes.\n\n```\nc list |\n  grep ok\n```\n\n"
+	if out := string(m.Out()); !strings.HasSuffix(out, expect) {
+		t.Errorf("expected examples %q, got %q", expect, out)
+	}
+
+	z := &Zsh{}
+	if err := z.Completion(parser.Model.Node, "c"); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(z.Out()), `"list[List volumes (e.g. c list)]"`) {
+		t.Errorf("expected example in command description")
+	}
+}
+
+type EnvT struct {
+	Server string `help:"Server to use." env:"C_SERVER"`
+	List   struct {
+		Status string `help:"Only list volumes with this status." env:"C_STATUS" default:"ok"`
+		Host   string `help:"Host to list." env:"C_SERVER,C_HOST"`
+	} `cmd:"" help:"List volumes."`
+}
+
+func TestManEnvironment(t *testing.T) {
+	parser := kong.Must(&EnvT{})
+	m := &Man{Section: 1, Template: "{{options}}\n{{globals}}\n{{environment}}"}
+	if err := m.Manual(parser.Model.Node, "list", "", "c"); err != nil {
+		t.Fatal(err)
+	}
+	const expect = "## Environment\n\n" +
+		"`C_HOST`\n:   Sets **--host**: host to list.\n\n" +
+		"`C_SERVER`\n:   Sets **--host**: host to list. Sets **--server**: server to use.\n\n" +
+		"`C_STATUS`\n:   Sets **--status**: only list volumes with this status, \"ok\" when not set.\n\n"
+	if out := string(m.Out()); !strings.HasSuffix(out, expect) {
+		t.Errorf("expected environment %q, got %q", expect, out)
+	}
+	if envs := parser.Model.Node.Flags[1].Envs; envs[0] != "C_SERVER" {
+		t.Errorf("expected envs to be left alone, got %v", envs)
+	}
+}
+
+type ExitT struct {
+	Volume struct {
+		List struct{} `cmd:"" help:"List volumes." exitcodes:"3=no volumes found"`
+		Rm   struct{} `cmd:"" help:"Remove volumes." exitcodes:"3=volume is in use,1=removal failed"`
+	} `cmd:"" help:"Manage volumes." exitcodes:"3=volume not found"`
+}
+
+func TestManExitStatus(t *testing.T) {
+	parser := kong.Must(&ExitT{})
+	m := &Man{Section: 1, Template: `{{exitstatus}}`, ExitCodes: []ExitCode{{0, "Success."}, {1, "Failure."}, {2, "Usage error."}}}
+	if err := m.Manual(parser.Model.Node, "volume rm", "", "c"); err != nil {
+		t.Fatal(err)
+	}
+	const expect = "## Exit Status\n\n`0`\n:   Success.\n\n`1`\n:   removal failed\n\n`2`\n:   Usage error.\n\n`3`\n:   volume is in use\n\n"
+	if out := string(m.Out()); !strings.HasSuffix(out, expect) {
+		t.Errorf("expected exit status %q, got %q", expect, out)
+	}
+
+	m = &Man{Section: 1, Template: `{{exitstatus}}`}
+	if err := m.Manual(parser.Model.Node, "volume", "", "c"); err != nil {
+		t.Fatal(err)
+	}
+	if out := string(m.Out()); !strings.HasSuffix(out, "`3`\n:   volume not found\n\n") {
+		t.Errorf("expected inherited exit status, got %q", out)
+	}
+}
+
+type SeeAlsoT struct {
+	Volume struct {
+		List struct{} `cmd:"" help:"List volumes."`
+		Rm   struct{} `cmd:"" help:"Remove volumes."`
+	} `cmd:"" help:"Manage volumes." seealso:"mount(8),c-disk" files:"/etc/c/volumes=The volume database."`
+}
+
+func TestManSections(t *testing.T) {
+	parser := kong.Must(&SeeAlsoT{})
+	m := &Man{Section: 1, Template: "{{files}}{{authors}}{{bugs}}{{seealso}}",
+		Files:   []File{{"~/.c", "The user configuration."}},
+		SeeAlso: []string{"kong(7)"},
+		Authors: []string{"Miek Gieben <miek@miek.nl>"},
+		Bugs:    "Report bugs at https://github.com/miekg/king/issues.",
+	}
+	if err := m.Manual(parser.Model.Node, "volume", "", "c"); err != nil {
+		t.Fatal(err)
+	}
+	const expect = "## Files\n\n*/etc/c/volumes*\n:   The volume database.\n\n*~/.c*\n:   The user configuration.\n\n" +
+		"## Authors\n\nMiek Gieben <miek@miek.nl>\n\n" +
+		"## Bugs\n\nReport bugs at https://github.com/miekg/king/issues.\n\n" +
+		"## See Also\n\n*c*(1), *c-volume-list*(1), *c-volume-rm*(1), *mount*(8), *c-disk*(1), *kong*(7)\n"
+	if out := string(m.Out()); !strings.HasSuffix(out, expect) {
+		t.Errorf("expected sections %q, got %q", expect, out)
+	}
+}
+
+func TestManDeprecated(t *testing.T) {
+	parser := kong.Must(&D{})
+	m := &Man{Section: 1, Template: "{{options}}"}
+	if err := m.Manual(parser.Model.Node, "", "d", ""); err != nil {
+		t.Fatal(err)
+	}
+	if out := string(m.Out()); !strings.Contains(out, ":   (Deprecated: use --format=json) output json.") {
+		t.Errorf("expected deprecation in manual page, got\n%s", out)
+	}
+}
+
+func TestManVariadic(t *testing.T) {
+	parser := kong.Must(&V{})
+	m := &Man{Section: 1, Template: "{{synopsis}}"}
+	if err := m.Manual(parser.Model.Node, "rm", "", "v"); err != nil {
+		t.Fatal(err)
+	}
+	if out := string(m.Out()); !strings.Contains(out, "`v rm` *[SERVER]* *[VOLUMES]*...") {
+		t.Errorf("expected variadic argument in synopsis, got\n%s", out)
+	}
+}
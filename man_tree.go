@@ -0,0 +1,93 @@
+package king
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/alecthomas/kong"
+)
+
+// Pages is a list of manual pages as generated by [Man.Tree].
+type Pages []*Man
+
+// Name returns the name of the manual page, this is also the file name without the section.
+func (m *Man) Name() string { return m.name }
+
+// Tree generates a manual page for k and for every non-hidden command below it. Each page is named after
+// the command path, with the words joined with a dash: rootname-volume-list. All exported fields are copied
+// from m.
+//
+// Problems found in any of the commands are collected and returned in a single *[Error], the pages of these
+// commands are left out.
+func (m *Man) Tree(k *kong.Node, rootname string) (Pages, error) {
+	pages := Pages{}
+	ps := []Problem{}
+	var walk func(n *kong.Node, path []string) error
+	walk = func(n *kong.Node, path []string) error {
+		page := &Man{Section: m.Section, Area: m.Area, WorkGroup: m.WorkGroup, Template: m.Template, CSS: m.CSS, FrontMatter: m.FrontMatter,
+			Examples: m.Examples, ExitCodes: m.ExitCodes, Files: m.Files, SeeAlso: m.SeeAlso, Authors: m.Authors, Bugs: m.Bugs,
+			Flags: m.Flags}
+		page.links = pageLinks(n, rootname, path)
+
+		altname := strings.Join(append([]string{rootname}, path...), " ")
+		err := page.Manual(k, strings.Join(path, " "), altname, rootname)
+		switch kerr, ok := err.(*Error); {
+		case ok:
+			ps = append(ps, kerr.Problems...)
+		case err != nil:
+			return err
+		default:
+			page.name = manName(rootname, path)
+			pages = append(pages, page)
+		}
+
+		for _, c := range n.Children {
+			if c.Type == kong.CommandNode && !c.Hidden {
+				if err := walk(c, append(path[:len(path):len(path)], c.Name)); err != nil {
+					return err
+				}
+			}
+		}
+		return nil
+	}
+	if err := walk(k, nil); err != nil {
+		return nil, err
+	}
+	return pages, problems(ps)
+}
+
+// manName returns the name of the manual page for the command path.
+func manName(rootname string, path []string) string {
+	return strings.Join(append([]string{rootname}, path...), "-")
+}
+
+// pageLinks returns the names of the pages of the subcommands of cmd, keyed by the subcommand's name.
+func pageLinks(cmd *kong.Node, rootname string, path []string) map[string]string {
+	links := map[string]string{}
+	for _, c := range cmd.Children {
+		if c.Type == kong.CommandNode && !c.Hidden {
+			links[commandName(c)] = manName(rootname, append(path[:len(path):len(path)], c.Name))
+		}
+	}
+	return links
+}
+
+// Write writes all pages in man format to the directory dir, each to the file name.section.
+func (p Pages) Write(dir string) error {
+	for _, m := range p {
+		f, err := os.Create(filepath.Join(dir, fmt.Sprintf("%s.%d", m.name, m.Section)))
+		if err != nil {
+			return err
+		}
+		if err := m.Write(f); err != nil {
+			f.Close()
+			return err
+		}
+		if err := f.Close(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
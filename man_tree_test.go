@@ -0,0 +1,66 @@
+package king
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/alecthomas/kong"
+)
+
+func TestManTree(t *testing.T) {
+	parser := kong.Must(&T{}, kong.Description("The c command."))
+	m := &Man{Section: 1, Area: "User Commands", WorkGroup: "The hard working team"}
+	pages, err := m.Tree(parser.Model.Node, "c")
+	if err != nil {
+		t.Fatal(err)
+	}
+	names := []string{}
+	for _, p := range pages {
+		names = append(names, p.Name())
+	}
+	const expect = "c c-do c-more c-even-more c-even-more-do-even-more c-even-more-what-even-more"
+	if got := strings.Join(names, " "); got != expect {
+		t.Errorf("expected pages %q, got %q", expect, got)
+	}
+
+	seealso := "## See Also\n\n*c*(1), *c-even-more-do-even-more*(1), *c-even-more-what-even-more*(1)\n"
+	if !strings.Contains(string(pages[3].Out()), seealso) {
+		t.Errorf("expected See Also section %q, got %s", seealso, pages[3].Out())
+	}
+	synopsis := "`c even-more` *[OPTION]*... do-even-more|what-even-more\n\n`c more`"
+	if !strings.Contains(string(pages[3].Out()), synopsis) {
+		t.Errorf("expected synopsis %q, got %s", synopsis, pages[3].Out())
+	}
+
+	dir := t.TempDir()
+	if err := pages.Write(dir); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "c-even-more-do-even-more.1")); err != nil {
+		t.Error(err)
+	}
+}
+
+type TreeErr struct {
+	Good struct{} `cmd:"" help:"A good command."`
+	Bad  struct{} `cmd:"" help:"A bad command." exitcodes:"two=usage"`
+}
+
+func TestManTreeProblems(t *testing.T) {
+	parser := kong.Must(&TreeErr{})
+	m := &Man{Section: 1}
+	pages, err := m.Tree(parser.Model.Node, "c")
+	kerr, ok := err.(*Error)
+	if !ok || len(kerr.Problems) != 1 || kerr.Problems[0].Path != "c bad" {
+		t.Fatalf("expected a problem for c bad, got %v", err)
+	}
+	names := []string{}
+	for _, p := range pages {
+		names = append(names, p.Name())
+	}
+	if got := strings.Join(names, " "); got != "c c-good" {
+		t.Errorf("expected pages %q, got %q", "c c-good", got)
+	}
+}
@@ -0,0 +1,305 @@
+package king
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"reflect"
+	"strings"
+
+	"github.com/alecthomas/kong"
+)
+
+// Nushell is a nushell completion generator. It generates "extern" definitions for each command.
+type Nushell struct {
+	name       string
+	completion []byte
+	Flags      []*kong.Flag // Any global flags that the should Application Node have.
+	Dynamic    bool         // If true only a shim is generated that calls the [CompleteName] command of the application.
+	Validate   bool         // If true Write checks the completion with [Validate] first.
+}
+
+func (n *Nushell) Out() []byte { return n.completion }
+
+func (n *Nushell) Write(w ...io.Writer) error {
+	if n.completion == nil {
+		return fmt.Errorf("no completion")
+	}
+	if n.Validate {
+		if err := Validate("nushell", n.completion); err != nil {
+			return err
+		}
+	}
+	if len(w) > 0 {
+		w[0].Write(n.completion)
+	}
+	return os.WriteFile(n.name+".nu", n.completion, 0644)
+}
+
+func (n *Nushell) Completion(k *kong.Node, altname string) error {
+	if err := check(k, n.Flags); err != nil {
+		return err
+	}
+	addFlags(k, n.Flags)
+	format := `# nushell completion for %[1]s
+# generated by king (https://github.com/miekg/king) for kong
+
+`
+	var out strings.Builder
+	if altname == "" {
+		n.name = k.Name
+	} else {
+		n.name = altname
+		k.Name = altname
+	}
+	fmt.Fprintf(&out, format, n.name)
+	if n.Dynamic {
+		n.shim(&out, k)
+	} else {
+		n.gen(&out, k)
+	}
+	n.completion = []byte(out.String())
+	return nil
+}
+
+// nuPath returns the space separated command path of the node, the last element is replaced with name.
+func nuPath(cmd *kong.Node, name string) string {
+	if cmd.Parent == nil {
+		return name
+	}
+	return nuPath(cmd.Parent, cmd.Parent.Name) + " " + name
+}
+
+// nuType returns the nushell type for the value v.
+func nuType(v *kong.Value) string {
+	if !v.Target.IsValid() {
+		return "string"
+	}
+	kind := v.Target.Kind()
+	if v.IsSlice() {
+		kind = v.Target.Type().Elem().Kind()
+	}
+	switch kind {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return "int"
+	case reflect.Float32, reflect.Float64:
+		return "number"
+	}
+	return "string"
+}
+
+// nuPaths completes the files or directories for the word being completed, taken from the context of a
+// completer.
+var nuPaths = map[string]string{
+	"path":      `try { ls -a ($"($context | split row -r '\s+' | last)*" | into glob) | get name } catch { [] }`,
+	"directory": `try { ls -a ($"($context | split row -r '\s+' | last)*" | into glob) | where type == dir | get name } catch { [] }`,
+}
+
+// completer returns the name of the custom completer for v and the body of that completer. If v doesn't need
+// one, the empty strings are returned. If v is only completed with files or directories, the nushell type
+// for these is returned as the body, with an empty name. The enums, the completion tag and the environment variables are all
+// completed, as with the other shells.
+func (n Nushell) completer(v *kong.Value, path string, envs []string) (string, string) {
+	parts := []string{}
+	typ := "" // path or directory
+	values := []string{}
+	for _, e := range v.EnumSlice() {
+		if strings.TrimSpace(e) != "" {
+			values = append(values, nuQuote(e))
+		}
+	}
+	if len(values) > 0 {
+		parts = append(parts, "["+strings.Join(values, " ")+"]")
+	}
+	if comp := v.Tag.Get("completion"); comp != "" && !strings.HasPrefix(comp, "@") {
+		if strings.HasPrefix(comp, "<") && strings.HasSuffix(comp, ">") {
+			action := toAction(comp[1:len(comp)-1], "nushell")
+			switch action {
+			case "path", "directory":
+				typ = action
+				parts = append(parts, nuPaths[action])
+			case "": // unknown action, reported by Lint
+			default:
+				parts = append(parts, action)
+			}
+		} else {
+			parts = append(parts, fmt.Sprintf("^sh -c %s | str trim | split row -r '\\s+'", nuQuote(comp)))
+		}
+	}
+	if len(envs) > 0 {
+		vars := []string{}
+		for _, env := range envs {
+			vars = append(vars, "$env."+env+"?")
+		}
+		parts = append(parts, "["+strings.Join(vars, " ")+"] | compact")
+	}
+	switch {
+	case len(parts) == 0:
+		return "", ""
+	case len(parts) == 1 && typ != "":
+		return "", typ
+	case len(parts) == 1:
+		return "nu-complete " + path + " " + v.Name, parts[0]
+	}
+	body := nuExpr(parts[0])
+	for _, p := range parts[1:] {
+		body += " | append " + nuExpr(p)
+	}
+	return "nu-complete " + path + " " + v.Name, body
+}
+
+// nuExpr returns the pipeline p in parentheses, so it can be used as an argument. A list is returned as is.
+func nuExpr(p string) string {
+	if strings.HasPrefix(p, "[") && strings.HasSuffix(p, "]") {
+		return p
+	}
+	return "(" + p + ")"
+}
+
+// nuQuote returns s as a raw nushell string.
+func nuQuote(s string) string {
+	hashes := "#"
+	for strings.Contains(s, "'"+hashes) {
+		hashes += "#"
+	}
+	if !strings.Contains(s, "'") {
+		return "'" + s + "'"
+	}
+	return "r" + hashes + "'" + s + "'" + hashes
+}
+
+// nuComment returns help as a single line comment.
+func nuComment(help string) string {
+	help = strings.Join(strings.Fields(help), " ")
+	if help == "" {
+		return ""
+	}
+	return "  # " + help
+}
+
+// nuDef returns the definition of the completer name with body, the context is only given when the body uses it.
+func nuDef(name, body string) string {
+	params := ""
+	if strings.Contains(body, "$context") {
+		params = "context: string"
+	}
+	return fmt.Sprintf("def %q [%s] {\n  %s\n}\n\n", name, params, body)
+}
+
+// writeFlag writes the signature line for the flag f, any needed completer is written to defs.
+func (n Nushell) writeFlag(buf, defs io.StringWriter, f *kong.Flag, path string) {
+	short := ""
+	if f.Short != 0 {
+		short = fmt.Sprintf("(-%c)", f.Short)
+	}
+	typ := ""
+	if !f.IsBool() && !f.IsCounter() {
+		typ = ": " + nuType(f.Value)
+		name, body := n.completer(f.Value, path, flagEnvs(f))
+		switch {
+		case name != "":
+			writeString(defs, nuDef(name, body))
+			typ += fmt.Sprintf("@%q", name)
+		case body != "":
+			typ = ": " + body
+		}
+	}
+	writeString(buf, fmt.Sprintf("  --%s%s%s%s\n", f.Name, short, typ, nuComment(flagHelp(f))))
+	if neg := negation(f); neg != "" {
+		writeString(buf, fmt.Sprintf("  %s%s\n", neg, nuComment(flagHelp(f))))
+	}
+}
+
+// writePositional writes the signature line for the positional argument p, any needed completer is written to defs.
+func (n Nushell) writePositional(buf, defs io.StringWriter, p *kong.Positional, path string) {
+	name := strings.ToLower(p.Name)
+	switch {
+	case variadic(p):
+		name = "..." + name
+	case !p.Required:
+		name += "?"
+	}
+	typ := nuType(p)
+	cname, body := n.completer(p, path, nil)
+	switch {
+	case cname != "":
+		writeString(defs, nuDef(cname, body))
+		typ += fmt.Sprintf("@%q", cname)
+	case body != "":
+		typ = body
+	}
+	writeString(buf, fmt.Sprintf("  %s: %s%s\n", name, typ, nuComment(plain(p.Help))))
+}
+
+func (n Nushell) gen(buf io.StringWriter, cmd *kong.Node) {
+	path := nuPath(cmd, cmd.Name)
+	defs := &strings.Builder{}
+	sig := &strings.Builder{}
+	for _, f := range cmd.Flags {
+		if f.Hidden {
+			continue
+		}
+		n.writeFlag(sig, defs, f, path)
+	}
+	// kong allows the flags of the parents after a subcommand, their completers are already written with the parent
+	for parent := cmd.Parent; parent != nil; parent = parent.Parent {
+		for _, f := range parent.Flags {
+			if f.Hidden {
+				continue
+			}
+			n.writeFlag(sig, &strings.Builder{}, f, nuPath(parent, parent.Name))
+		}
+	}
+	for _, p := range cmd.Positional {
+		n.writePositional(sig, defs, p, path)
+	}
+
+	writeString(buf, defs.String())
+	names := []string{cmd.Name}
+	if cmd.Parent != nil {
+		names = append(names, cmd.Aliases...)
+	}
+	for _, name := range names {
+		if cmd.Help != "" {
+			writeString(buf, "# "+plain(cmd.Help)+"\n")
+		}
+		writeString(buf, fmt.Sprintf("export extern %q [\n", nuPath(cmd, name)))
+		writeString(buf, sig.String())
+		writeString(buf, "]\n\n")
+	}
+
+	for _, c := range cmd.Children {
+		if c == nil || c.Hidden {
+			continue
+		}
+		n.gen(buf, c)
+	}
+}
+
+// shim writes an extern with a completer that calls the application to get the completions. If the application
+// asks for an action, the completions of that action are added.
+func (n Nushell) shim(buf io.StringWriter, cmd *kong.Node) {
+	format := `def "nu-complete %[1]s" [context: string] {
+  let words = ($context | split row -r '\s+' | skip 1)
+  let lines = (^%[1]s %[2]s -- ...$words | lines)
+  let cur = ($words | last)
+  let action = ($lines | where ($it | str starts-with ':') | each { str substring 1.. } | get 0?)
+  let values = ($lines | where not ($it | str starts-with ':') | split column "\t" value description)
+  let extra = match $action {
+    "file" => { try { ls -a ($"($cur)*" | into glob) | get name } catch { [] } }
+    "directory" => { try { ls -a ($"($cur)*" | into glob) | where type == dir | get name } catch { [] } }
+    "group" => { %[3]s }
+    "user" => { %[4]s }
+    "export" => { %[5]s }
+    _ => { [] }
+  }
+  $values | append ($extra | wrap value)
+}
+
+export extern "%[1]s" [
+  ...args: string@"nu-complete %[1]s"
+]
+`
+	writeString(buf, fmt.Sprintf(format, n.name, CompleteName, nushellActions["group"], nushellActions["user"], nushellActions["export"]))
+}
@@ -0,0 +1,127 @@
+package king
+
+import (
+	"bytes"
+	"os"
+	"os/exec"
+	"testing"
+
+	"github.com/alecthomas/kong"
+)
+
+// compnTest sources the completion file in nushell to make sure it parses.
+func compnTest(t *testing.T, completionfile string) {
+	if _, err := exec.LookPath("nu"); err != nil {
+		t.Skip("nu not found")
+	}
+	cmd := exec.Command("nu", "--no-config-file", "-c", "source "+completionfile)
+	if out, err := cmd.CombinedOutput(); err != nil {
+		t.Fatalf("%s: %s", err, out)
+	}
+}
+
+func TestNushell(t *testing.T) {
+	parser := kong.Must(&T{})
+	n := &Nushell{Flags: []*kong.Flag{manf}}
+	if err := n.Completion(parser.Model.Node, "myexe"); err != nil {
+		t.Fatal(err)
+	}
+	if err := n.Write(); err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove("myexe.nu")
+
+	tests := []string{
+		`export extern "myexe do" [`,
+		`export extern "myexe d" [`,
+		`  --status(-s): string@"nu-complete myexe do status"`,
+		`  ['ok' 'setup' 'dst' 'archive' 'rm']`,
+		`  --file: path  # complete this file`,
+		`  volume: string@"nu-complete myexe do volume"  # Volume to change.`,
+		`export extern "myexe even-more do-even-more" [`,
+		`  arg: string  # This is another arg.`,
+	}
+	for i := range tests {
+		if !bytes.Contains(n.Out(), []byte(tests[i])) {
+			t.Errorf("test %d, expected %q to be present, but did not find it", i, tests[i])
+		}
+	}
+	compnTest(t, "myexe.nu")
+}
+
+func TestNushellValues(t *testing.T) {
+	parser := kong.Must(&G{})
+	n := &Nushell{}
+	if err := n.Completion(parser.Model.Node, "g"); err != nil {
+		t.Fatal(err)
+	}
+	tests := []string{
+		`  ['eu' 'us'] | append ([$env.G_REGION?] | compact)`,
+		`def "nu-complete g config" [context: string] {
+  (try { ls -a ($"($context | split row -r '\s+' | last)*" | into glob) | get name } catch { [] }) | append ([$env.G_CONFIG?] | compact)`,
+		`  --config(-c): string@"nu-complete g config"`,
+		`  --dir: directory`,
+	}
+	for i := range tests {
+		if !bytes.Contains(n.Out(), []byte(tests[i])) {
+			t.Errorf("test %d, expected %q in completion, got\n%s", i, tests[i], n.Out())
+		}
+	}
+}
+
+func TestNushellShimActions(t *testing.T) {
+	parser := kong.Must(&G{})
+	n := &Nushell{Dynamic: true}
+	if err := n.Completion(parser.Model.Node, "g"); err != nil {
+		t.Fatal(err)
+	}
+	for _, action := range []string{"file", "directory", "group", "user", "export"} {
+		expect := `    "` + action + `" => {`
+		if !bytes.Contains(n.Out(), []byte(expect)) {
+			t.Errorf("expected %q in completion, got\n%s", expect, n.Out())
+		}
+	}
+}
+
+func TestNushellParentFlags(t *testing.T) {
+	parser := kong.Must(&G{})
+	n := &Nushell{}
+	if err := n.Completion(parser.Model.Node, "g"); err != nil {
+		t.Fatal(err)
+	}
+	expect := `export extern "g volume list" [
+  --status(-s): string@"nu-complete g volume list status"  # Only list volumes with status STATUS.
+  --json  # Output in json.
+  --yaml  # Output in yaml.
+  --label: string@"nu-complete g volume list label"  # Only list volumes with this label.
+  --tag: string@"nu-complete g volume list tag"  # Only list volumes with this tag.
+  --old  # (deprecated: use --status rm) List the removed volumes.
+  --help(-h)  # Show context-sensitive help.
+  --verbose(-v)  # Be more verbose, can be repeated.
+  --config(-c): string@"nu-complete g config"  # Use this configuration file.
+`
+	if !bytes.Contains(n.Out(), []byte(expect)) {
+		t.Errorf("expected %q in completion, got\n%s", expect, n.Out())
+	}
+	if bytes.Count(n.Out(), []byte(`def "nu-complete g volume list status"`)) != 1 {
+		t.Errorf("expected a single completer for --status, got\n%s", n.Out())
+	}
+}
+
+func TestNushellVariadic(t *testing.T) {
+	parser := kong.Must(&V{})
+	n := &Nushell{}
+	if err := n.Completion(parser.Model.Node, "v"); err != nil {
+		t.Fatal(err)
+	}
+	if err := n.Write(); err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove("v.nu")
+
+	expect := `  ...volumes: string@"nu-complete v rm volumes"`
+	if !bytes.Contains(n.Out(), []byte(expect)) {
+		t.Errorf("expected %q in completion, got\n%s", expect, n.Out())
+	}
+	compnTest(t, "v.nu")
+}
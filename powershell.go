@@ -0,0 +1,377 @@
+package king
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/alecthomas/kong"
+)
+
+// PowerShell is a PowerShell completion generator.
+type PowerShell struct {
+	name       string
+	completion []byte
+	Flags      []*kong.Flag // Any global flags that the should Application Node have.
+	Dynamic    bool         // If true only a shim is generated that calls the [CompleteName] command of the application.
+	Validate   bool         // If true Write checks the completion with [Validate] first.
+}
+
+func (p *PowerShell) Out() []byte { return p.completion }
+
+func (p *PowerShell) Write(w ...io.Writer) error {
+	if p.completion == nil {
+		return fmt.Errorf("no completion")
+	}
+	if p.Validate {
+		if err := Validate("powershell", p.completion); err != nil {
+			return err
+		}
+	}
+	if len(w) > 0 {
+		w[0].Write(p.completion)
+	}
+	return os.WriteFile(p.name+".ps1", p.completion, 0644)
+}
+
+func (p *PowerShell) Completion(k *kong.Node, altname string) error {
+	if err := check(k, p.Flags); err != nil {
+		return err
+	}
+	addFlags(k, p.Flags)
+	format := `# powershell completion for %[1]s
+# generated by king (https://github.com/miekg/king) for kong
+
+`
+	var out strings.Builder
+	if altname == "" {
+		p.name = k.Name
+	} else {
+		p.name = altname
+		k.Name = altname
+	}
+	fmt.Fprintf(&out, format, p.name)
+	if p.Dynamic {
+		p.shim(&out, k)
+	} else {
+		p.gen(&out, k)
+	}
+	p.completion = []byte(out.String())
+	return nil
+}
+
+// psQuote returns s as a single quoted PowerShell string. PowerShell also sees the typographic single quotes as
+// quotes, these are doubled as well.
+func psQuote(s string) string {
+	return "'" + strings.NewReplacer("'", "''", "\u2018", "\u2018\u2018", "\u2019", "\u2019\u2019", "\u201a", "\u201a\u201a", "\u201b", "\u201b\u201b").Replace(s) + "'"
+}
+
+// psQuoteFunc is the PowerShell function that quotes a completion when it has characters that PowerShell
+// treats specially, as with psQuote.
+const psQuoteFunc = `    function _king_quote([string]$text) {
+        if ($text -notmatch '[\s''"` + "`" + `$&|;,(){}@#<>\u2018-\u201e]') { return $text }
+        "'" + ($text -replace '[''\u2018-\u201b]', '$0$0') + "'"
+    }
+`
+
+// psPath returns the ; separated path of the node, this is used as the key in the generated lookup tables.
+func psPath(n *kong.Node) string { return commandPath(n, ";") }
+
+// values returns the PowerShell statements that output the possible values for v.
+func (p PowerShell) values(v *kong.Value, envs []string) []string {
+	values := []string{}
+	if enums := v.EnumSlice(); len(enums) > 0 {
+		quoted := []string{}
+		for _, e := range enums {
+			if strings.TrimSpace(e) != "" {
+				quoted = append(quoted, psQuote(e))
+			}
+		}
+		if len(quoted) > 0 {
+			values = append(values, strings.Join(quoted, ", "))
+		}
+	}
+	if comp := v.Tag.Get("completion"); comp != "" && !strings.HasPrefix(comp, "@") {
+		if strings.HasPrefix(comp, "<") && strings.HasSuffix(comp, ">") {
+			if action := toAction(comp[1:len(comp)-1], "powershell"); action != "" {
+				values = append(values, action)
+			}
+		} else {
+			values = append(values, fmt.Sprintf("& sh -c %s | ForEach-Object { -split $_ }", psQuote(comp)))
+		}
+	}
+	for _, env := range envs {
+		values = append(values, "$env:"+env)
+	}
+	return values
+}
+
+func (p PowerShell) writeValues(buf io.StringWriter, values []string, indent string) {
+	for _, v := range values {
+		writeString(buf, fmt.Sprintf("%s%s | ForEach-Object { if ($_) { _king_result $_ 'ParameterValue' $_ } }\n", indent, v))
+	}
+}
+
+// writeTables writes the entries for the $commands, $values and $flags lookup tables for cmd and all its
+// children. The $flags table has the path and name of the flag for all the names of the flag, the repeatable
+// and deprecated flags are added to the $repeatable and $deprecated sets.
+func (p PowerShell) writeTables(commands, values, flags io.StringWriter, cmd *kong.Node) {
+	path := psPath(cmd)
+	for _, f := range cmd.Flags {
+		if f.Hidden {
+			continue
+		}
+		key := psQuote(path + ";--" + f.Name)
+		for _, name := range flagNames(f) {
+			writeString(flags, fmt.Sprintf("    $flags[%s] = %s\n", psQuote(path+";"+name), key))
+		}
+		if repeatable(f.Value) {
+			writeString(flags, fmt.Sprintf("    [void]$repeatable.Add(%s)\n", key))
+		}
+		if _, ok := deprecated(f); ok {
+			writeString(flags, fmt.Sprintf("    [void]$deprecated.Add(%s)\n", key))
+		}
+	}
+	for _, f := range cmd.Flags {
+		if f.Hidden || f.IsBool() || f.IsCounter() {
+			continue
+		}
+		writeString(values, fmt.Sprintf("    [void]$values.Add(%s)\n", psQuote(path+";--"+f.Name)))
+		if f.Short != 0 {
+			writeString(values, fmt.Sprintf("    [void]$values.Add(%s)\n", psQuote(fmt.Sprintf("%s;-%c", path, f.Short))))
+		}
+	}
+	for _, c := range cmd.Children {
+		if c == nil || c.Hidden {
+			continue
+		}
+		for _, name := range append([]string{c.Name}, c.Aliases...) {
+			writeString(commands, fmt.Sprintf("    $commands[%s] = %s\n", psQuote(path+";"+name), psQuote(psPath(c))))
+		}
+		p.writeTables(commands, values, flags, c)
+	}
+}
+
+// writeFlagValues writes the switch cases that complete the values of the flags of cmd and all its children.
+func (p PowerShell) writeFlagValues(buf io.StringWriter, cmd *kong.Node) {
+	path := psPath(cmd)
+	for _, f := range cmd.Flags {
+		if f.Hidden || f.IsBool() || f.IsCounter() {
+			continue
+		}
+		values := p.values(f.Value, flagEnvs(f))
+		if len(values) == 0 {
+			continue
+		}
+		keys := []string{psQuote(path + ";--" + f.Name)}
+		if f.Short != 0 {
+			keys = append(keys, psQuote(fmt.Sprintf("%s;-%c", path, f.Short)))
+		}
+		for _, k := range keys {
+			writeString(buf, fmt.Sprintf("        %s {\n", k))
+			p.writeValues(buf, values, "            ")
+			writeString(buf, "        }\n")
+		}
+	}
+	for _, c := range cmd.Children {
+		if c == nil || c.Hidden {
+			continue
+		}
+		p.writeFlagValues(buf, c)
+	}
+}
+
+// writeCommand writes the switch case that completes the flags, subcommands and positional arguments of cmd and
+// recurses into its children.
+func (p PowerShell) writeCommand(buf io.StringWriter, cmd *kong.Node) {
+	writeString(buf, fmt.Sprintf("                %s {\n", psQuote(psPath(cmd))))
+	writeString(buf, "                    if ($wordToComplete.StartsWith('-')) {\n")
+	for _, f := range cmd.Flags {
+		if f.Hidden {
+			continue
+		}
+		writeString(buf, fmt.Sprintf("                        _king_result %s 'ParameterName' %s\n", psQuote("--"+f.Name), psQuote(flagHelp(f))))
+		if f.Short != 0 {
+			writeString(buf, fmt.Sprintf("                        _king_result %s 'ParameterName' %s\n", psQuote(fmt.Sprintf("-%c", f.Short)), psQuote(flagHelp(f))))
+		}
+		if neg := negation(f); neg != "" {
+			writeString(buf, fmt.Sprintf("                        _king_result %s 'ParameterName' %s\n", psQuote(neg), psQuote(flagHelp(f))))
+		}
+	}
+	writeString(buf, "                    } else {\n")
+	for _, c := range cmd.Children {
+		if c == nil || c.Hidden {
+			continue
+		}
+		for _, name := range append([]string{c.Name}, c.Aliases...) {
+			writeString(buf, fmt.Sprintf("                        _king_result %s 'Command' %s\n", psQuote(name), psQuote(plain(c.Help))))
+		}
+	}
+	positional := &strings.Builder{}
+	for i, a := range cmd.Positional {
+		values := p.values(a, nil)
+		if len(values) == 0 {
+			continue
+		}
+		if variadic(a) {
+			writeString(positional, fmt.Sprintf("                            { $_ -ge %d } {\n", i))
+		} else {
+			writeString(positional, fmt.Sprintf("                            %d {\n", i))
+		}
+		p.writeValues(positional, values, "                                ")
+		writeString(positional, "                            }\n")
+	}
+	if positional.Len() > 0 {
+		writeString(buf, "                        switch ($pos) {\n")
+		writeString(buf, positional.String())
+		writeString(buf, "                        }\n")
+	}
+	writeString(buf, "                    }\n")
+	writeString(buf, "                }\n")
+
+	for _, c := range cmd.Children {
+		if c == nil || c.Hidden {
+			continue
+		}
+		p.writeCommand(buf, c)
+	}
+}
+
+func (p PowerShell) gen(buf io.StringWriter, cmd *kong.Node) {
+	writeString(buf, fmt.Sprintf("Register-ArgumentCompleter -Native -CommandName %s -ScriptBlock {\n", psQuote(cmd.Name)))
+	writeString(buf, `    param($wordToComplete, $commandAst, $cursorPosition)
+
+    $wordToComplete = $wordToComplete -replace '^[''"]', ''
+
+`+psQuoteFunc+`
+    function _king_result([string]$text, [string]$type, [string]$tip) {
+        if ($tip -eq '') { $tip = $text }
+        [System.Management.Automation.CompletionResult]::new($text, $text, $type, $tip)
+    }
+
+    function _king_owner([string]$flag) {
+        for ($j = $path.Count - 1; $j -ge 0; $j--) {
+            if ($flags.ContainsKey("$($path[$j]);$flag")) { return $path[$j] }
+        }
+    }
+
+    function _king_key($result) {
+        $key = "$command;$($result.CompletionText)"
+        if ($result.ResultType -eq 'ParameterName' -and $flags.ContainsKey($key)) { $flags[$key] }
+    }
+
+    function _king_paths([string]$word, [switch]$directory) {
+        $parent = if ($word) { Split-Path -Parent $word } else { '' }
+        Get-ChildItem -Path "$word*" -Directory:$directory -ErrorAction SilentlyContinue | ForEach-Object {
+            if ($parent) { Join-Path $parent $_.Name } else { $_.Name }
+        }
+    }
+
+    $commands = [System.Collections.Generic.Dictionary[string,string]]::new([System.StringComparer]::Ordinal)
+    $values = [System.Collections.Generic.HashSet[string]]::new([System.StringComparer]::Ordinal)
+    $flags = [System.Collections.Generic.Dictionary[string,string]]::new([System.StringComparer]::Ordinal)
+    $repeatable = [System.Collections.Generic.HashSet[string]]::new([System.StringComparer]::Ordinal)
+    $deprecated = [System.Collections.Generic.HashSet[string]]::new([System.StringComparer]::Ordinal)
+    $given = [System.Collections.Generic.HashSet[string]]::new([System.StringComparer]::Ordinal)
+`)
+	commands := &strings.Builder{}
+	values := &strings.Builder{}
+	flags := &strings.Builder{}
+	p.writeTables(commands, values, flags, cmd)
+	writeString(buf, commands.String())
+	writeString(buf, values.String())
+	writeString(buf, flags.String())
+
+	writeString(buf, fmt.Sprintf("\n    $command = %s\n", psQuote(cmd.Name)))
+	writeString(buf, `    $path = @($command)
+    $value = ''
+    $prefix = ''
+    $pos = 0
+    $words = @($commandAst.CommandElements | Where-Object { $_.Extent.EndOffset -lt $cursorPosition } | ForEach-Object { $_.ToString() })
+    for ($i = 1; $i -lt $words.Count; $i++) {
+        $w = $words[$i]
+        if ($w.StartsWith('-')) {
+            $name = $w.Split('=')[0]
+            $owner = _king_owner $name
+            if ($owner) {
+                [void]$given.Add($flags["$owner;$name"])
+                if (-not $w.Contains('=') -and $values.Contains("$owner;$name")) {
+                    if ($i -eq $words.Count - 1) { $value = "$owner;$w" } else { $i++ }
+                }
+            }
+            continue
+        }
+        if ($commands.ContainsKey("$command;$w")) {
+            $command = $commands["$command;$w"]
+            $path += $command
+            $pos = 0
+            continue
+        }
+        $pos++
+    }
+    if (-not $value -and $wordToComplete -match '^(-[^=]+)=(.*)$') {
+        $owner = _king_owner $Matches[1]
+        if ($owner -and $values.Contains("$owner;$($Matches[1])")) {
+            $value = "$owner;$($Matches[1])"
+            $prefix = "$($Matches[1])="
+            $wordToComplete = $Matches[2]
+        }
+    }
+
+    $completions = switch -Exact -CaseSensitive ($value) {
+`)
+	p.writeFlagValues(buf, cmd)
+	writeString(buf, `        '' {
+            switch -Exact -CaseSensitive ($command) {
+`)
+	p.writeCommand(buf, cmd)
+	writeString(buf, `            }
+        }
+    }
+    $completions = @($completions | Where-Object { $_.CompletionText.StartsWith($wordToComplete, [System.StringComparison]::Ordinal) } | Where-Object {
+        $key = _king_key $_
+        -not $key -or $repeatable.Contains($key) -or -not $given.Contains($key)
+    })
+    if ($completions.Count -gt 1) {
+        $completions = @($completions | Where-Object { -not $deprecated.Contains("$(_king_key $_)") })
+    }
+    $completions | ForEach-Object {
+        [System.Management.Automation.CompletionResult]::new((_king_quote ($prefix + $_.CompletionText)), $_.ListItemText, $_.ResultType, $_.ToolTip)
+    }
+}
+`)
+}
+
+// shim writes a completer that calls the application to get the completions.
+func (p PowerShell) shim(buf io.StringWriter, cmd *kong.Node) {
+	format := `Register-ArgumentCompleter -Native -CommandName %[1]s -ScriptBlock {
+    param($wordToComplete, $commandAst, $cursorPosition)
+
+    $wordToComplete = $wordToComplete -replace '^[''"]', ''
+
+%[3]s
+    $words = @($commandAst.CommandElements | Where-Object { $_.Extent.EndOffset -lt $cursorPosition } | Select-Object -Skip 1 | ForEach-Object { $_.ToString() })
+    $words += $wordToComplete
+    & %[1]s %[2]s -- @words 2>$null | ForEach-Object {
+        if ($_.StartsWith(':')) {
+            $results = switch ($_.Substring(1)) {
+                'file' { Get-ChildItem -Path "$wordToComplete*" -Name -ErrorAction SilentlyContinue }
+                'directory' { Get-ChildItem -Path "$wordToComplete*" -Name -Directory -ErrorAction SilentlyContinue }
+                'group' { Get-Content /etc/group -ErrorAction SilentlyContinue | ForEach-Object { $_.Split(':')[0] } }
+                'user' { Get-Content /etc/passwd -ErrorAction SilentlyContinue | ForEach-Object { $_.Split(':')[0] } }
+                'export' { Get-ChildItem Env: | ForEach-Object Name }
+            }
+            $results | Where-Object { $_.StartsWith($wordToComplete, [System.StringComparison]::Ordinal) } | ForEach-Object {
+                [System.Management.Automation.CompletionResult]::new((_king_quote $_), $_, 'ParameterValue', $_)
+            }
+            return
+        }
+        $value, $tip = $_ -split "` + "`" + `t", 2
+        if (-not $tip) { $tip = $value }
+        [System.Management.Automation.CompletionResult]::new((_king_quote $value), $value, 'ParameterValue', $tip)
+    }
+}
+`
+	writeString(buf, fmt.Sprintf(format, psQuote(p.name), CompleteName, psQuoteFunc))
+}
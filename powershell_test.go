@@ -0,0 +1,256 @@
+package king
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"os/exec"
+	"testing"
+
+	"github.com/alecthomas/kong"
+)
+
+func comppTest(t *testing.T, completionfile, exe string) []byte {
+	if _, err := exec.LookPath("pwsh"); err != nil {
+		t.Skip("pwsh not found")
+	}
+	script := fmt.Sprintf(`. ./%s; (TabExpansion2 -inputScript %s -cursorColumn %d).CompletionMatches | ForEach-Object { $_.CompletionText }`,
+		completionfile, psQuote(exe), len(exe))
+	cmd := exec.Command("pwsh", "-NoProfile", "-NonInteractive", "-Command", script)
+	out, err := cmd.Output()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return bytes.TrimSpace(out)
+}
+
+func TestPowerShell(t *testing.T) {
+	parser := kong.Must(&T{})
+	p := &PowerShell{Flags: []*kong.Flag{manf}}
+	if err := p.Completion(parser.Model.Node, "myexe"); err != nil {
+		t.Fatal(err)
+	}
+	if err := p.Write(); err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove("myexe.ps1")
+
+	tests := []struct {
+		exe    string
+		expect string
+	}{
+		{"myexe --", "--help\n--man"},
+		{"myexe --m", "--man"},
+		{"myexe ev", "even-more"},
+		{"myexe do --status s", "setup"},
+		{"myexe even-more do-even-more --s", "--string"},
+		{"myexe do ", "a\nb\nc"},
+	}
+
+	for i := range tests {
+		out := comppTest(t, "myexe.ps1", tests[i].exe)
+		if string(out) != tests[i].expect {
+			t.Errorf("test %d, expected %q, got %q", i, tests[i].expect, string(out))
+		}
+	}
+}
+
+func TestPowerShellGen(t *testing.T) {
+	parser := kong.Must(&T{})
+	p := &PowerShell{}
+	if err := p.Completion(parser.Model.Node, "myexe"); err != nil {
+		t.Fatal(err)
+	}
+	for _, expect := range []string{
+		`Register-ArgumentCompleter -Native -CommandName 'myexe' -ScriptBlock {`,
+		`    $commands['myexe;d'] = 'myexe;do'`,
+		`    [void]$values.Add('myexe;do;--status')`,
+		`            'ok', 'setup', 'dst', 'archive', 'rm' | ForEach-Object { if ($_) { _king_result $_ 'ParameterValue' $_ } }`,
+		`            & sh -c 'echo bla bloep' | ForEach-Object { -split $_ } | ForEach-Object { if ($_) { _king_result $_ 'ParameterValue' $_ } }`,
+		`            _king_paths $wordToComplete | ForEach-Object { if ($_) { _king_result $_ 'ParameterValue' $_ } }`,
+		`                        _king_result '--status' 'ParameterName' 'Set the status for this volume to STATUS. See VOLUME STATUS section.'`,
+		`                                & sh -c 'echo a b c' | ForEach-Object { -split $_ } | ForEach-Object { if ($_) { _king_result $_ 'ParameterValue' $_ } }`,
+	} {
+		if !bytes.Contains(p.Out(), []byte(expect)) {
+			t.Errorf("expected %q in completion, got\n%s", expect, p.Out())
+		}
+	}
+}
+
+func TestPowerShellQuote(t *testing.T) {
+	tests := []struct {
+		s      string
+		expect string
+	}{
+		{"it's", `'it''s'`},
+		{"it’s", "'it’’s'"},
+		{`$HOME "x"`, `'$HOME "x"'`},
+	}
+	for _, tc := range tests {
+		if got := psQuote(tc.s); got != tc.expect {
+			t.Errorf("for %q, expected %q, got %q", tc.s, tc.expect, got)
+		}
+	}
+}
+
+func TestPowerShellParentFlag(t *testing.T) {
+	parser := kong.Must(&G{})
+	p := &PowerShell{}
+	if err := p.Completion(parser.Model.Node, "g"); err != nil {
+		t.Fatal(err)
+	}
+	for _, expect := range []string{
+		`            if ($flags.ContainsKey("$($path[$j]);$flag")) { return $path[$j] }`,
+		`            $path += $command`,
+		`    if (-not $value -and $wordToComplete -match '^(-[^=]+)=(.*)$') {`,
+	} {
+		if !bytes.Contains(p.Out(), []byte(expect)) {
+			t.Errorf("expected %q in completion, got\n%s", expect, p.Out())
+		}
+	}
+	if err := os.WriteFile("g.ps1", p.Out(), 0644); err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove("g.ps1")
+
+	tests := []struct {
+		exe    string
+		expect string
+	}{
+		{"g volume list --config go.m", "go.mod"},
+		{"g volume list --config go.mod ", "vol1\nvol2"},
+		{"g server --region=e", "--region=eu"},
+	}
+	for i := range tests {
+		if out := comppTest(t, "g.ps1", tests[i].exe); string(out) != tests[i].expect {
+			t.Errorf("test %d, expected %q, got %q", i, tests[i].expect, string(out))
+		}
+	}
+}
+
+func TestPowerShellQuoteFunc(t *testing.T) {
+	parser := kong.Must(&G{})
+	for _, dynamic := range []bool{false, true} {
+		p := &PowerShell{Dynamic: dynamic}
+		if err := p.Completion(parser.Model.Node, "g"); err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Contains(p.Out(), []byte(psQuoteFunc)) || !bytes.Contains(p.Out(), []byte("(_king_quote ")) {
+			t.Errorf("expected completions to be quoted with _king_quote, got\n%s", p.Out())
+		}
+		if bytes.Contains(p.Out(), []byte("-like")) {
+			t.Errorf("expected no wildcard matching of the word, got\n%s", p.Out())
+		}
+	}
+}
+
+func TestPowerShellFlags(t *testing.T) {
+	parser := kong.Must(&G{})
+	p := &PowerShell{}
+	if err := p.Completion(parser.Model.Node, "g"); err != nil {
+		t.Fatal(err)
+	}
+	for _, expect := range []string{
+		`    $flags['g;-v'] = 'g;--verbose'`,
+		`    $flags['g;--no-color'] = 'g;--color'`,
+		`    [void]$repeatable.Add('g;volume;list;--tag')`,
+		`    [void]$deprecated.Add('g;volume;list;--old')`,
+		`                [void]$given.Add($flags["$owner;$name"])`,
+	} {
+		if !bytes.Contains(p.Out(), []byte(expect)) {
+			t.Errorf("expected %q in completion, got\n%s", expect, p.Out())
+		}
+	}
+	if bytes.Contains(p.Out(), []byte("'g;--debug'")) {
+		t.Errorf("expected no hidden flags in the tables, got\n%s", p.Out())
+	}
+	if err := os.WriteFile("g.ps1", p.Out(), 0644); err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove("g.ps1")
+
+	tests := []struct {
+		exe    string
+		expect string
+	}{
+		{"g volume list --o", "--old"},
+		{"g volume list --", "--status\n-s\n--json\n--yaml\n--label\n--tag"},
+		{"g volume list --json --j", ""},
+		{"g volume list --tag blue --t", "--tag"},
+		{"g -c go.mod --c", "--color"},
+		{"g --no-color --co", "--config"},
+	}
+	for i := range tests {
+		if out := comppTest(t, "g.ps1", tests[i].exe); string(out) != tests[i].expect {
+			t.Errorf("test %d, expected %q, got %q", i, tests[i].expect, string(out))
+		}
+	}
+}
+
+func TestPowerShellDeprecated(t *testing.T) {
+	parser := kong.Must(&D{})
+	p := &PowerShell{}
+	if err := p.Completion(parser.Model.Node, "d"); err != nil {
+		t.Fatal(err)
+	}
+	if err := p.Write(); err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove("d.ps1")
+
+	tests := []struct {
+		exe    string
+		expect string
+	}{
+		{"d --", "--help\n-h\n--format\n--version"},
+		{"d --verb", "--verbose"},
+		{"d --j", "--json"},
+	}
+	for i := range tests {
+		if out := comppTest(t, "d.ps1", tests[i].exe); string(out) != tests[i].expect {
+			t.Errorf("test %d, expected %q, got %q", i, tests[i].expect, string(out))
+		}
+	}
+}
+
+func TestPowerShellRepeatable(t *testing.T) {
+	parser := kong.Must(&R{})
+	p := &PowerShell{}
+	if err := p.Completion(parser.Model.Node, "r"); err != nil {
+		t.Fatal(err)
+	}
+	if err := p.Write(); err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove("r.ps1")
+
+	tests := []struct {
+		exe    string
+		expect string
+	}{
+		{"r -v --verb", "--verbose"},
+		{"r --tag a --ta", "--tag"},
+		{"r --status ok --st", ""},
+	}
+	for i := range tests {
+		if out := comppTest(t, "r.ps1", tests[i].exe); string(out) != tests[i].expect {
+			t.Errorf("test %d, expected %q, got %q", i, tests[i].expect, string(out))
+		}
+	}
+}
+
+func TestPowerShellVariadic(t *testing.T) {
+	parser := kong.Must(&V{})
+	p := &PowerShell{}
+	if err := p.Completion(parser.Model.Node, "v"); err != nil {
+		t.Fatal(err)
+	}
+	if err := p.Write(); err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove("v.ps1")
+
+	if out := comppTest(t, "v.ps1", "v rm s1 a "); string(out) != "a\nb" {
+		t.Errorf("expected %q, got %q", "a\nb", string(out))
+	}
+}
@@ -0,0 +1,59 @@
+package king
+
+import (
+	"strings"
+	"sync"
+
+	"github.com/alecthomas/kong"
+)
+
+// Args is given to a [Predictor] when completing.
+type Args struct {
+	Word  string      // The (partial) word being completed.
+	Words []string    // All words on the command line, the executable's name is not included.
+	Node  *kong.Node  // The command being completed.
+	Value *kong.Value // The flag or positional argument being completed.
+
+	// Flags holds the values of the flags given before the word, keyed by the flag's name, as in "region". A
+	// flag that doesn't take a value has "true", or "false" when it is negated. Flags that are repeated have
+	// multiple values.
+	Flags map[string][]string
+}
+
+// Predictor computes completions in Go. It is attached to a flag or positional argument with a completion tag
+// that has the name the predictor is registered under, prefixed with a '@': `completion:"@volumes"`. Predictors
+// are only used with dynamic completion, see [Complete], the static completion scripts ignore them.
+type Predictor interface {
+	// Predict returns the candidates for a.Word. The candidates do not have to be filtered on a.Word.
+	Predict(a Args) []Candidate
+}
+
+// PredictFunc is an adapter to allow the use of an ordinary function as a [Predictor].
+type PredictFunc func(a Args) []Candidate
+
+// Predict implements [Predictor].
+func (p PredictFunc) Predict(a Args) []Candidate { return p(a) }
+
+var (
+	predictorsMu sync.RWMutex
+	predictors   = map[string]Predictor{}
+)
+
+// RegisterPredictor registers p under name, for use as `completion:"@name"`. Registering under an existing
+// name replaces the previous predictor.
+func RegisterPredictor(name string, p Predictor) {
+	predictorsMu.Lock()
+	defer predictorsMu.Unlock()
+	predictors[name] = p
+}
+
+// predictor returns the predictor for the completion tag comp, or nil if comp does not name one.
+func predictor(comp string) Predictor {
+	name, ok := strings.CutPrefix(comp, "@")
+	if !ok {
+		return nil
+	}
+	predictorsMu.RLock()
+	defer predictorsMu.RUnlock()
+	return predictors[name]
+}
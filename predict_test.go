@@ -0,0 +1,66 @@
+package king
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/alecthomas/kong"
+)
+
+type P struct {
+	Rm struct {
+		Server string   `help:"Server to use." completion:"@servers"`
+		Region string   `help:"Region of the server."`
+		Force  bool     `help:"Remove in use volumes." negatable:""`
+		Volume []string `arg:"" help:"Volume to remove." completion:"@volumes"`
+	} `cmd:"" help:"Remove volumes."`
+}
+
+func TestPredictor(t *testing.T) {
+	RegisterPredictor("servers", PredictFunc(func(a Args) []Candidate {
+		if region := a.Flags["region"]; len(region) > 0 {
+			return []Candidate{{Value: region[len(region)-1] + "-s1"}}
+		}
+		return []Candidate{{"s1", "server one"}, {"s2", "server two"}}
+	}))
+	RegisterPredictor("volumes", PredictFunc(func(a Args) []Candidate {
+		if a.Node.Name != "rm" {
+			t.Errorf("expected node %q, got %q", "rm", a.Node.Name)
+		}
+		if force := a.Flags["force"]; len(force) > 0 && force[0] == "false" {
+			return []Candidate{{Value: "vol1"}}
+		}
+		return []Candidate{{Value: "vol1"}, {Value: "vol2"}, {Value: "data"}}
+	}))
+
+	parser := kong.Must(&P{})
+	tests := []struct {
+		words  []string
+		expect string
+	}{
+		{[]string{"rm", "--server", ""}, "s1 s2"},
+		{[]string{"rm", "--server=s"}, "--server=s1 --server=s2"},
+		{[]string{"rm", "v"}, "vol1 vol2"},
+		{[]string{"rm", "--region", "eu", "--server", ""}, "eu-s1"},
+		{[]string{"rm", "--region=us", "--server="}, "--server=us-s1"},
+		{[]string{"rm", "--no-force", "v"}, "vol1"},
+	}
+	for i := range tests {
+		cands, _ := Complete(parser.Model.Node, tests[i].words)
+		values := []string{}
+		for _, c := range cands {
+			values = append(values, c.Value)
+		}
+		if got := strings.Join(values, " "); got != tests[i].expect {
+			t.Errorf("test %d, expected %q, got %q", i, tests[i].expect, got)
+		}
+	}
+
+	z := &Zsh{}
+	if err := z.Completion(parser.Model.Node, "p"); err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(string(z.Out()), "@") {
+		t.Errorf("expected predictor to be ignored in static completion")
+	}
+}
@@ -0,0 +1,200 @@
+# bash completion for g
+# generated by king (https://github.com/miekg/king) for kong
+
+_g_filter() {
+  COMP_REPLY=()
+  local words="$1"
+  local deprecated="$2"
+  local repeatable=" $3 "
+  local result=()
+
+  if [[ "${cur:0:1}" == "-" ]]; then
+    local given=" ${COMP_WORDS[*]:1:COMP_CWORD-1} "
+    for word in $words; do
+      if [[ "${word:0:1}" == "-" && "$repeatable" != *" $word "* ]]; then
+        [[ "$given" == *" $word "* || "$given" == *" $word="* ]] && continue
+      fi
+      result+=("$word")
+    done
+    words="${result[*]}"
+    if [[ -n "$deprecated" ]]; then
+      local matches=($(compgen -W "$words $deprecated" -- "$cur"))
+      if [[ ${#matches[@]} -eq 1 ]]; then
+        echo "${matches[0]}"
+        return
+      fi
+    fi
+    echo "$words"
+
+  else
+    for word in $words; do
+      [[ "${word:0:1}" != "-" ]] && result+=("$word")
+    done
+    echo "${result[*]}"
+  fi
+}
+
+_g_describe() {
+  (( ${#COMPREPLY[@]} > 1 )) || return
+  local -A help=()
+  local word desc width=0 cmds=() flags=()
+  while IFS=$'\t' read -r word desc; do
+    [[ -n "$word" ]] && help[$word]=$desc
+  done <<< "$1"
+  for word in "${COMPREPLY[@]}"; do
+    (( ${#word} > width )) && width=${#word}
+  done
+  for word in "${COMPREPLY[@]}"; do
+    desc=${help[$word]}
+    if [[ -n "$desc" ]]; then
+      printf -v desc "%-*s  (%s)" "$width" "$word" "$desc"
+      (( ${#desc} > ${COLUMNS:-80} - 2 )) && desc="${desc:0:${COLUMNS:-80}-6}...)"
+    else
+      desc=$word
+    fi
+    if [[ "${word:0:1}" == "-" ]]; then
+      flags+=("$desc")
+    else
+      cmds+=("$desc")
+    fi
+  done
+  COMPREPLY=("${cmds[@]}" "${flags[@]}")
+  compopt -o nosort 2>/dev/null
+}
+
+_g_completions() {
+  local line=${COMP_LINE:0:COMP_POINT} words=() rest w i
+  for (( i = 0; i <= COMP_CWORD; i++ )); do
+    w=${COMP_WORDS[i]}
+    rest=${line#"${line%%[![:space:]]*}"}
+    if (( i > 1 )) && [[ "$rest" == "$line" ]]; then
+      words[-1]+=$w
+    elif (( i > 0 )); then
+      words+=("$w")
+    fi
+    line=${rest#"$w"}
+  done
+  local cur=${words[-1]}
+  local cmd='g' pos=0 skip=0 flag="" dashdash=0 prefix=""
+  COMPREPLY=()
+
+  for w in "${words[@]:0:${#words[@]}-1}"; do
+    if (( dashdash )); then
+      (( pos++ ))
+      continue
+    fi
+    if (( skip )); then
+      skip=0
+      continue
+    fi
+    [[ "$w" == "--" ]] && dashdash=1 && continue
+    case "$cmd $w" in
+      'g volume'|'g vol')
+        cmd='g volume'; pos=0 ;;
+      'g server')
+        cmd='g server'; pos=0 ;;
+      'g volume list'|'g volume ls')
+        cmd='g volume list'; pos=0 ;;
+      'g volume create')
+        cmd='g volume create'; pos=0 ;;
+      'g volume list --status'|'g volume list '*' --status'|'g volume list -s'|'g volume list '*' -s')
+        skip=1; flag=$w ;;
+      'g volume list --label'|'g volume list '*' --label')
+        skip=1; flag=$w ;;
+      'g volume list --tag'|'g volume list '*' --tag')
+        skip=1; flag=$w ;;
+      'g volume create --size'|'g volume create '*' --size')
+        skip=1; flag=$w ;;
+      'g volume create --dir'|'g volume create '*' --dir')
+        skip=1; flag=$w ;;
+      'g server --region'|'g server '*' --region')
+        skip=1; flag=$w ;;
+      'g server --owner'|'g server '*' --owner')
+        skip=1; flag=$w ;;
+      'g --config'|'g '*' --config'|'g -c'|'g '*' -c')
+        skip=1; flag=$w ;;
+      *' -'*)
+        ;;
+      *)
+        (( pos++ )) ;;
+    esac
+  done
+  if (( ! skip && ! dashdash )) && [[ "$cur" == -*=* ]]; then
+    skip=1; flag=${cur%%=*}; cur=${cur#*=}
+  fi
+  rest=${COMP_WORDS[COMP_CWORD]}
+  [[ -z "${rest//[=:]/}" ]] && rest=""
+  [[ "$cur" == *"$rest" ]] && prefix=${cur%"$rest"}
+
+  if (( skip )); then
+    case "$cmd $flag" in
+      'g volume list --status'|'g volume list '*' --status'|'g volume list -s'|'g volume list '*' -s')
+        while read -r; do COMPREPLY+=("$REPLY"); done < <(compgen -W "$(_g_filter "ok setup rm")" -- "$cur")
+        ;;
+      'g volume list --label'|'g volume list '*' --label')
+        compopt -o nospace; while read -r; do COMPREPLY+=("$REPLY"); done < <(compgen -S = -W "$(echo team owner)" -- "$cur")
+        ;;
+      'g volume list --tag'|'g volume list '*' --tag')
+        while read -r; do COMPREPLY+=("$REPLY"); done < <(compgen -W "$(_g_filter "$(echo blue green)")" -- "$cur")
+        ;;
+      'g volume create --size'|'g volume create '*' --size')
+        while read -r; do COMPREPLY+=("$REPLY"); done < <(compgen -W "$(_g_filter "$G_SIZE $G_VOLUME_SIZE")" -- "$cur")
+        ;;
+      'g volume create --dir'|'g volume create '*' --dir')
+        while read -r; do COMPREPLY+=("$REPLY"); done < <(compgen -A directory -- "$cur")
+        ;;
+      'g server --region'|'g server '*' --region')
+        while read -r; do COMPREPLY+=("$REPLY"); done < <(compgen -W "$(_g_filter "$G_REGION")" -- "$cur")
+        ;;
+      'g server --owner'|'g server '*' --owner')
+        while read -r; do COMPREPLY+=("$REPLY"); done < <(compgen -A user -- "$cur")
+        ;;
+      'g --config'|'g '*' --config'|'g -c'|'g '*' -c')
+        while read -r; do COMPREPLY+=("$REPLY"); done < <(compgen -W "$(_g_filter "$G_CONFIG")" -- "$cur")
+        ;;
+    esac
+    [[ -n "$prefix" ]] && COMPREPLY=("${COMPREPLY[@]#"$prefix"}")
+    return
+  fi
+
+  case "$cmd" in
+    'g')
+      case $pos in
+        *) while read -r; do COMPREPLY+=("$REPLY"); done < <(compgen -W "$(_g_filter "volume vol server --help -h --verbose -v --config -c --color --no-color" "" "--verbose -v")" -- "$cur"); _g_describe $'volume\tManage volumes.\nvol\tManage volumes.\nserver\tManage servers.\n--help\tShow context-sensitive help.\n-h\tShow context-sensitive help.\n--verbose\tBe more verbose, can be repeated.\n-v\tBe more verbose, can be repeated.\n--config\tUse this configuration file.\n-c\tUse this configuration file.\n--color\tUse colors in the output.\n--no-color\tUse colors in the output.\n'
+           ;;
+      esac
+      ;;
+    'g volume')
+      case $pos in
+        *) while read -r; do COMPREPLY+=("$REPLY"); done < <(compgen -W "$(_g_filter "list ls create" "" "")" -- "$cur"); _g_describe $'list\tList the volumes (e.g. g volume list --status ok)\nls\tList the volumes (e.g. g volume list --status ok)\ncreate\tCreate a volume.\n'
+           ;;
+      esac
+      ;;
+    'g volume list')
+      case $pos in
+        0) while read -r; do COMPREPLY+=("$REPLY"); done < <(compgen -W "$(_g_filter "--status -s --json --yaml --label --tag $(echo vol1 vol2)" "--old" "--label --tag")" -- "$cur"); _g_describe $'--status\tOnly list volumes with status STATUS.\n-s\tOnly list volumes with status STATUS.\n--json\tOutput in json.\n--yaml\tOutput in yaml.\n--label\tOnly list volumes with this label.\n--tag\tOnly list volumes with this tag.\n--old\t(deprecated: use --status rm) List the removed volumes.\n'
+           ;;
+        *) while read -r; do COMPREPLY+=("$REPLY"); done < <(compgen -W "$(_g_filter "--status -s --json --yaml --label --tag" "--old" "--label --tag")" -- "$cur"); _g_describe $'--status\tOnly list volumes with status STATUS.\n-s\tOnly list volumes with status STATUS.\n--json\tOutput in json.\n--yaml\tOutput in yaml.\n--label\tOnly list volumes with this label.\n--tag\tOnly list volumes with this tag.\n--old\t(deprecated: use --status rm) List the removed volumes.\n'
+           ;;
+      esac
+      ;;
+    'g volume create')
+      case $pos in
+        0) while read -r; do COMPREPLY+=("$REPLY"); done < <(compgen -W "$(_g_filter "--size --dir" "" "")" -- "$cur"); _g_describe $'--size\tSize of the volume in GB.\n--dir\tDirectory to create the volume in.\n'
+           ;;
+        *) while read -r; do COMPREPLY+=("$REPLY"); done < <(compgen -W "$(_g_filter "--size --dir $(echo s1 s2)" "" "")" -- "$cur"); _g_describe $'--size\tSize of the volume in GB.\n--dir\tDirectory to create the volume in.\n'
+           ;;
+      esac
+      ;;
+    'g server')
+      case $pos in
+        0) while read -r; do COMPREPLY+=("$REPLY"); done < <(compgen -W "$(_g_filter "--region --owner $(echo web:data db:logs)" "" "")" -- "$cur"); _g_describe $'--region\tRegion of the server.\n--owner\tOwner of the server.\n'
+           ;;
+        *) while read -r; do COMPREPLY+=("$REPLY"); done < <(compgen -W "$(_g_filter "--region --owner" "" "")" -- "$cur"); _g_describe $'--region\tRegion of the server.\n--owner\tOwner of the server.\n'
+           ;;
+      esac
+      ;;
+  esac
+  [[ -n "$prefix" ]] && COMPREPLY=("${COMPREPLY[@]#"$prefix"}")
+} &&
+complete -F _g_completions g
@@ -0,0 +1,36 @@
+# bash completion for g
+# generated by king (https://github.com/miekg/king) for kong
+
+_g_completions() {
+  local line=${COMP_LINE:0:COMP_POINT} words=() rest w i
+  for (( i = 0; i <= COMP_CWORD; i++ )); do
+    w=${COMP_WORDS[i]}
+    rest=${line#"${line%%[![:space:]]*}"}
+    if (( i > 1 )) && [[ "$rest" == "$line" ]]; then
+      words[-1]+=$w
+    elif (( i > 0 )); then
+      words+=("$w")
+    fi
+    line=${rest#"$w"}
+  done
+  local cur=${words[-1]}
+  rest=$cur
+  [[ ${COMP_WORDS[COMP_CWORD]} != "$cur" ]] && rest=${cur##*[=:]}
+  local prefix=${cur:0:${#cur}-${#rest}}
+  local action="" descs=""
+  COMPREPLY=()
+
+  while IFS= read -r line; do
+    case "$line" in
+      :*) action=${line:1} ;;
+      *) COMPREPLY+=("${line%%$'\t'*}"); descs+=$line$'\n' ;;
+    esac
+  done < <(g __complete -- "${words[@]}" 2>/dev/null)
+  [[ ${#COMPREPLY[@]} -eq 1 && ${COMPREPLY[0]} == *= ]] && compopt -o nospace
+  [[ -n "$prefix" ]] && COMPREPLY=("${COMPREPLY[@]#"$prefix"}")
+
+  if [[ -n "$action" ]]; then
+    while read -r; do COMPREPLY+=("$REPLY"); done < <(compgen -A "$action" -- "$rest")
+  fi
+} &&
+complete -F _g_completions g
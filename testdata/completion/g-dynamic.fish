@@ -0,0 +1,24 @@
+# fish shell completion for g
+# generated by king (https://github.com/miekg/king) for kong
+function __g_complete
+    set -l tokens (commandline -opc) (commandline -ct)
+    set -e tokens[1]
+    for line in (g __complete -- $tokens 2>/dev/null)
+        switch $line
+            case ':file'
+                __fish_complete_path (commandline -ct)
+            case ':directory'
+                __fish_complete_directories (commandline -ct)
+            case ':user'
+                __fish_complete_users
+            case ':group'
+                __fish_complete_groups
+            case ':export'
+                set -n
+            case '*'
+                echo $line
+        end
+    end
+end
+
+complete -c g -f -a '(__g_complete)'
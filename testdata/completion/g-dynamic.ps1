@@ -0,0 +1,34 @@
+# powershell completion for g
+# generated by king (https://github.com/miekg/king) for kong
+
+Register-ArgumentCompleter -Native -CommandName 'g' -ScriptBlock {
+    param($wordToComplete, $commandAst, $cursorPosition)
+
+    $wordToComplete = $wordToComplete -replace '^[''"]', ''
+
+    function _king_quote([string]$text) {
+        if ($text -notmatch '[\s''"`$&|;,(){}@#<>\u2018-\u201e]') { return $text }
+        "'" + ($text -replace '[''\u2018-\u201b]', '$0$0') + "'"
+    }
+
+    $words = @($commandAst.CommandElements | Where-Object { $_.Extent.EndOffset -lt $cursorPosition } | Select-Object -Skip 1 | ForEach-Object { $_.ToString() })
+    $words += $wordToComplete
+    & 'g' __complete -- @words 2>$null | ForEach-Object {
+        if ($_.StartsWith(':')) {
+            $results = switch ($_.Substring(1)) {
+                'file' { Get-ChildItem -Path "$wordToComplete*" -Name -ErrorAction SilentlyContinue }
+                'directory' { Get-ChildItem -Path "$wordToComplete*" -Name -Directory -ErrorAction SilentlyContinue }
+                'group' { Get-Content /etc/group -ErrorAction SilentlyContinue | ForEach-Object { $_.Split(':')[0] } }
+                'user' { Get-Content /etc/passwd -ErrorAction SilentlyContinue | ForEach-Object { $_.Split(':')[0] } }
+                'export' { Get-ChildItem Env: | ForEach-Object Name }
+            }
+            $results | Where-Object { $_.StartsWith($wordToComplete, [System.StringComparison]::Ordinal) } | ForEach-Object {
+                [System.Management.Automation.CompletionResult]::new((_king_quote $_), $_, 'ParameterValue', $_)
+            }
+            return
+        }
+        $value, $tip = $_ -split "`t", 2
+        if (-not $tip) { $tip = $value }
+        [System.Management.Automation.CompletionResult]::new((_king_quote $value), $value, 'ParameterValue', $tip)
+    }
+}
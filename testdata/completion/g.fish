@@ -0,0 +1,103 @@
+# fish shell completion for g
+# generated by king (https://github.com/miekg/king) for kong
+
+complete -c g -f
+
+function __g_state
+    set -l cmd 'g'
+    set -l pos 0
+    set -l skip 0
+    set -l tokens (commandline -opc)
+    set -e tokens[1]
+    for w in $tokens
+        if test $skip -eq 1
+            set skip 0
+            continue
+        end
+        switch "$cmd $w"
+            case 'g volume' 'g vol'
+                set cmd 'g volume'
+                set pos 0
+            case 'g server'
+                set cmd 'g server'
+                set pos 0
+            case 'g --config' 'g * --config' 'g -c' 'g * -c'
+                set skip 1
+            case 'g volume list' 'g volume ls'
+                set cmd 'g volume list'
+                set pos 0
+            case 'g volume create'
+                set cmd 'g volume create'
+                set pos 0
+            case 'g volume list --status' 'g volume list * --status' 'g volume list -s' 'g volume list * -s'
+                set skip 1
+            case 'g volume list --label' 'g volume list * --label'
+                set skip 1
+            case 'g volume list --tag' 'g volume list * --tag'
+                set skip 1
+            case 'g volume create --size' 'g volume create * --size'
+                set skip 1
+            case 'g volume create --dir' 'g volume create * --dir'
+                set skip 1
+            case 'g server --region' 'g server * --region'
+                set skip 1
+            case 'g server --owner' 'g server * --owner'
+                set skip 1
+            case '* -*'
+            case '*'
+                set pos (math $pos + 1)
+        end
+    end
+    echo $cmd
+    echo $pos
+end
+
+function __g_command
+    set -l state (__g_state)
+    test "$state[1]" = "$argv[1]"
+end
+
+function __g_positional
+    set -l state (__g_state)
+    test "$state[1]" = "$argv[1]" -a "$state[2]" = "$argv[2]"
+end
+
+function __g_positional_from
+    set -l state (__g_state)
+    test "$state[1]" = "$argv[1]" -a "$state[2]" -ge "$argv[2]"
+end
+
+# g
+complete -c g -f -n "__g_command 'g'" -a volume -d 'Manage volumes.'
+complete -c g -f -n "__g_command 'g'" -a vol -d 'Manage volumes.'
+complete -c g -f -n "__g_command 'g'" -a server -d 'Manage servers.'
+complete -c g -n "__g_command 'g'; and not __fish_seen_argument -l help -s h" -s h -l help -d 'Show context-sensitive help.'
+complete -c g -n "__g_command 'g'" -s v -l verbose -d 'Be more verbose, can be repeated.'
+complete -c g -n "__g_command 'g'; and not __fish_seen_argument -l config -s c" -x -a '(__fish_complete_path (commandline -ct)) $G_CONFIG' -s c -l config -d 'Use this configuration file.'
+complete -c g -n "__g_command 'g'; and not __fish_seen_argument -l color -l no-color" -l color -d 'Use colors in the output.'
+complete -c g -n "__g_command 'g'; and not __fish_seen_argument -l color -l no-color" -l no-color -d 'Use colors in the output.'
+
+# g volume
+complete -c g -f -n "__g_command 'g volume'" -a list -d 'List the volumes (e.g. g volume list --status ok)'
+complete -c g -f -n "__g_command 'g volume'" -a ls -d 'List the volumes (e.g. g volume list --status ok)'
+complete -c g -f -n "__g_command 'g volume'" -a create -d 'Create a volume.'
+
+# g volume list
+complete -c g -n "__g_command 'g volume list'; and not __fish_seen_argument -l status -s s" -x -a 'ok setup rm' -s s -l status -d 'Only list volumes with status STATUS.'
+complete -c g -n "__g_command 'g volume list'; and not __fish_seen_argument -l json -l yaml" -l json -d 'Output in json.'
+complete -c g -n "__g_command 'g volume list'; and not __fish_seen_argument -l yaml -l json" -l yaml -d 'Output in yaml.'
+complete -c g -n "__g_command 'g volume list'" -x -a '(echo team owner | string split -n " " | string replace -r \'$\' =)' -l label -d 'Only list volumes with this label.'
+complete -c g -n "__g_command 'g volume list'" -x -a '(echo blue green | string split -n " ")' -l tag -d 'Only list volumes with this tag.'
+complete -c g -n "__g_command 'g volume list'; and not __fish_seen_argument -l old" -l old -d '(deprecated: use --status rm) List the removed volumes.'
+complete -c g -f -n "__g_positional 'g volume list' 0" -a '(echo vol1 vol2 | string split -n " ")' -d 'Only list the volume with this name.'
+
+# g volume create
+complete -c g -n "__g_command 'g volume create'; and not __fish_seen_argument -l size" -x -a '$G_SIZE $G_VOLUME_SIZE' -l size -d 'Size of the volume in GB.'
+complete -c g -n "__g_command 'g volume create'; and not __fish_seen_argument -l dir" -x -a '(__fish_complete_directories (commandline -ct))' -l dir -d 'Directory to create the volume in.'
+complete -c g -f -n "__g_positional_from 'g volume create' 1" -a '(echo s1 s2 | string split -n " ")' -d 'Servers to create the volume on.'
+
+# g server
+complete -c g -n "__g_command 'g server'; and not __fish_seen_argument -l region" -x -a 'eu us $G_REGION' -l region -d 'Region of the server.'
+complete -c g -n "__g_command 'g server'; and not __fish_seen_argument -l owner" -x -a '(__fish_complete_users)' -l owner -d 'Owner of the server.'
+complete -c g -f -n "__g_positional 'g server' 0" -a '(echo web:data db:logs | string split -n " ")' -d 'Name of the server.'
+
@@ -0,0 +1,234 @@
+# powershell completion for g
+# generated by king (https://github.com/miekg/king) for kong
+
+Register-ArgumentCompleter -Native -CommandName 'g' -ScriptBlock {
+    param($wordToComplete, $commandAst, $cursorPosition)
+
+    $wordToComplete = $wordToComplete -replace '^[''"]', ''
+
+    function _king_quote([string]$text) {
+        if ($text -notmatch '[\s''"`$&|;,(){}@#<>\u2018-\u201e]') { return $text }
+        "'" + ($text -replace '[''\u2018-\u201b]', '$0$0') + "'"
+    }
+
+    function _king_result([string]$text, [string]$type, [string]$tip) {
+        if ($tip -eq '') { $tip = $text }
+        [System.Management.Automation.CompletionResult]::new($text, $text, $type, $tip)
+    }
+
+    function _king_owner([string]$flag) {
+        for ($j = $path.Count - 1; $j -ge 0; $j--) {
+            if ($flags.ContainsKey("$($path[$j]);$flag")) { return $path[$j] }
+        }
+    }
+
+    function _king_key($result) {
+        $key = "$command;$($result.CompletionText)"
+        if ($result.ResultType -eq 'ParameterName' -and $flags.ContainsKey($key)) { $flags[$key] }
+    }
+
+    function _king_paths([string]$word, [switch]$directory) {
+        $parent = if ($word) { Split-Path -Parent $word } else { '' }
+        Get-ChildItem -Path "$word*" -Directory:$directory -ErrorAction SilentlyContinue | ForEach-Object {
+            if ($parent) { Join-Path $parent $_.Name } else { $_.Name }
+        }
+    }
+
+    $commands = [System.Collections.Generic.Dictionary[string,string]]::new([System.StringComparer]::Ordinal)
+    $values = [System.Collections.Generic.HashSet[string]]::new([System.StringComparer]::Ordinal)
+    $flags = [System.Collections.Generic.Dictionary[string,string]]::new([System.StringComparer]::Ordinal)
+    $repeatable = [System.Collections.Generic.HashSet[string]]::new([System.StringComparer]::Ordinal)
+    $deprecated = [System.Collections.Generic.HashSet[string]]::new([System.StringComparer]::Ordinal)
+    $given = [System.Collections.Generic.HashSet[string]]::new([System.StringComparer]::Ordinal)
+    $commands['g;volume'] = 'g;volume'
+    $commands['g;vol'] = 'g;volume'
+    $commands['g;volume;list'] = 'g;volume;list'
+    $commands['g;volume;ls'] = 'g;volume;list'
+    $commands['g;volume;create'] = 'g;volume;create'
+    $commands['g;server'] = 'g;server'
+    [void]$values.Add('g;--config')
+    [void]$values.Add('g;-c')
+    [void]$values.Add('g;volume;list;--status')
+    [void]$values.Add('g;volume;list;-s')
+    [void]$values.Add('g;volume;list;--label')
+    [void]$values.Add('g;volume;list;--tag')
+    [void]$values.Add('g;volume;create;--size')
+    [void]$values.Add('g;volume;create;--dir')
+    [void]$values.Add('g;server;--region')
+    [void]$values.Add('g;server;--owner')
+    $flags['g;--help'] = 'g;--help'
+    $flags['g;-h'] = 'g;--help'
+    $flags['g;--verbose'] = 'g;--verbose'
+    $flags['g;-v'] = 'g;--verbose'
+    [void]$repeatable.Add('g;--verbose')
+    $flags['g;--config'] = 'g;--config'
+    $flags['g;-c'] = 'g;--config'
+    $flags['g;--color'] = 'g;--color'
+    $flags['g;--no-color'] = 'g;--color'
+    $flags['g;volume;list;--status'] = 'g;volume;list;--status'
+    $flags['g;volume;list;-s'] = 'g;volume;list;--status'
+    $flags['g;volume;list;--json'] = 'g;volume;list;--json'
+    $flags['g;volume;list;--yaml'] = 'g;volume;list;--yaml'
+    $flags['g;volume;list;--label'] = 'g;volume;list;--label'
+    [void]$repeatable.Add('g;volume;list;--label')
+    $flags['g;volume;list;--tag'] = 'g;volume;list;--tag'
+    [void]$repeatable.Add('g;volume;list;--tag')
+    $flags['g;volume;list;--old'] = 'g;volume;list;--old'
+    [void]$deprecated.Add('g;volume;list;--old')
+    $flags['g;volume;create;--size'] = 'g;volume;create;--size'
+    $flags['g;volume;create;--dir'] = 'g;volume;create;--dir'
+    $flags['g;server;--region'] = 'g;server;--region'
+    $flags['g;server;--owner'] = 'g;server;--owner'
+
+    $command = 'g'
+    $path = @($command)
+    $value = ''
+    $prefix = ''
+    $pos = 0
+    $words = @($commandAst.CommandElements | Where-Object { $_.Extent.EndOffset -lt $cursorPosition } | ForEach-Object { $_.ToString() })
+    for ($i = 1; $i -lt $words.Count; $i++) {
+        $w = $words[$i]
+        if ($w.StartsWith('-')) {
+            $name = $w.Split('=')[0]
+            $owner = _king_owner $name
+            if ($owner) {
+                [void]$given.Add($flags["$owner;$name"])
+                if (-not $w.Contains('=') -and $values.Contains("$owner;$name")) {
+                    if ($i -eq $words.Count - 1) { $value = "$owner;$w" } else { $i++ }
+                }
+            }
+            continue
+        }
+        if ($commands.ContainsKey("$command;$w")) {
+            $command = $commands["$command;$w"]
+            $path += $command
+            $pos = 0
+            continue
+        }
+        $pos++
+    }
+    if (-not $value -and $wordToComplete -match '^(-[^=]+)=(.*)$') {
+        $owner = _king_owner $Matches[1]
+        if ($owner -and $values.Contains("$owner;$($Matches[1])")) {
+            $value = "$owner;$($Matches[1])"
+            $prefix = "$($Matches[1])="
+            $wordToComplete = $Matches[2]
+        }
+    }
+
+    $completions = switch -Exact -CaseSensitive ($value) {
+        'g;--config' {
+            _king_paths $wordToComplete | ForEach-Object { if ($_) { _king_result $_ 'ParameterValue' $_ } }
+            $env:G_CONFIG | ForEach-Object { if ($_) { _king_result $_ 'ParameterValue' $_ } }
+        }
+        'g;-c' {
+            _king_paths $wordToComplete | ForEach-Object { if ($_) { _king_result $_ 'ParameterValue' $_ } }
+            $env:G_CONFIG | ForEach-Object { if ($_) { _king_result $_ 'ParameterValue' $_ } }
+        }
+        'g;volume;list;--status' {
+            'ok', 'setup', 'rm' | ForEach-Object { if ($_) { _king_result $_ 'ParameterValue' $_ } }
+        }
+        'g;volume;list;-s' {
+            'ok', 'setup', 'rm' | ForEach-Object { if ($_) { _king_result $_ 'ParameterValue' $_ } }
+        }
+        'g;volume;list;--label' {
+            & sh -c 'echo team owner' | ForEach-Object { -split $_ } | ForEach-Object { if ($_) { _king_result $_ 'ParameterValue' $_ } }
+        }
+        'g;volume;list;--tag' {
+            & sh -c 'echo blue green' | ForEach-Object { -split $_ } | ForEach-Object { if ($_) { _king_result $_ 'ParameterValue' $_ } }
+        }
+        'g;volume;create;--size' {
+            $env:G_SIZE | ForEach-Object { if ($_) { _king_result $_ 'ParameterValue' $_ } }
+            $env:G_VOLUME_SIZE | ForEach-Object { if ($_) { _king_result $_ 'ParameterValue' $_ } }
+        }
+        'g;volume;create;--dir' {
+            _king_paths $wordToComplete -directory | ForEach-Object { if ($_) { _king_result $_ 'ParameterValue' $_ } }
+        }
+        'g;server;--region' {
+            'eu', 'us' | ForEach-Object { if ($_) { _king_result $_ 'ParameterValue' $_ } }
+            $env:G_REGION | ForEach-Object { if ($_) { _king_result $_ 'ParameterValue' $_ } }
+        }
+        'g;server;--owner' {
+            Get-Content /etc/passwd -ErrorAction SilentlyContinue | ForEach-Object { $_.Split(':')[0] } | ForEach-Object { if ($_) { _king_result $_ 'ParameterValue' $_ } }
+        }
+        '' {
+            switch -Exact -CaseSensitive ($command) {
+                'g' {
+                    if ($wordToComplete.StartsWith('-')) {
+                        _king_result '--help' 'ParameterName' 'Show context-sensitive help.'
+                        _king_result '-h' 'ParameterName' 'Show context-sensitive help.'
+                        _king_result '--verbose' 'ParameterName' 'Be more verbose, can be repeated.'
+                        _king_result '-v' 'ParameterName' 'Be more verbose, can be repeated.'
+                        _king_result '--config' 'ParameterName' 'Use this configuration file.'
+                        _king_result '-c' 'ParameterName' 'Use this configuration file.'
+                        _king_result '--color' 'ParameterName' 'Use colors in the output.'
+                        _king_result '--no-color' 'ParameterName' 'Use colors in the output.'
+                    } else {
+                        _king_result 'volume' 'Command' 'Manage volumes.'
+                        _king_result 'vol' 'Command' 'Manage volumes.'
+                        _king_result 'server' 'Command' 'Manage servers.'
+                    }
+                }
+                'g;volume' {
+                    if ($wordToComplete.StartsWith('-')) {
+                    } else {
+                        _king_result 'list' 'Command' 'List the volumes.'
+                        _king_result 'ls' 'Command' 'List the volumes.'
+                        _king_result 'create' 'Command' 'Create a volume.'
+                    }
+                }
+                'g;volume;list' {
+                    if ($wordToComplete.StartsWith('-')) {
+                        _king_result '--status' 'ParameterName' 'Only list volumes with status STATUS.'
+                        _king_result '-s' 'ParameterName' 'Only list volumes with status STATUS.'
+                        _king_result '--json' 'ParameterName' 'Output in json.'
+                        _king_result '--yaml' 'ParameterName' 'Output in yaml.'
+                        _king_result '--label' 'ParameterName' 'Only list volumes with this label.'
+                        _king_result '--tag' 'ParameterName' 'Only list volumes with this tag.'
+                        _king_result '--old' 'ParameterName' '(deprecated: use --status rm) List the removed volumes.'
+                    } else {
+                        switch ($pos) {
+                            0 {
+                                & sh -c 'echo vol1 vol2' | ForEach-Object { -split $_ } | ForEach-Object { if ($_) { _king_result $_ 'ParameterValue' $_ } }
+                            }
+                        }
+                    }
+                }
+                'g;volume;create' {
+                    if ($wordToComplete.StartsWith('-')) {
+                        _king_result '--size' 'ParameterName' 'Size of the volume in GB.'
+                        _king_result '--dir' 'ParameterName' 'Directory to create the volume in.'
+                    } else {
+                        switch ($pos) {
+                            { $_ -ge 1 } {
+                                & sh -c 'echo s1 s2' | ForEach-Object { -split $_ } | ForEach-Object { if ($_) { _king_result $_ 'ParameterValue' $_ } }
+                            }
+                        }
+                    }
+                }
+                'g;server' {
+                    if ($wordToComplete.StartsWith('-')) {
+                        _king_result '--region' 'ParameterName' 'Region of the server.'
+                        _king_result '--owner' 'ParameterName' 'Owner of the server.'
+                    } else {
+                        switch ($pos) {
+                            0 {
+                                & sh -c 'echo web:data db:logs' | ForEach-Object { -split $_ } | ForEach-Object { if ($_) { _king_result $_ 'ParameterValue' $_ } }
+                            }
+                        }
+                    }
+                }
+            }
+        }
+    }
+    $completions = @($completions | Where-Object { $_.CompletionText.StartsWith($wordToComplete, [System.StringComparison]::Ordinal) } | Where-Object {
+        $key = _king_key $_
+        -not $key -or $repeatable.Contains($key) -or -not $given.Contains($key)
+    })
+    if ($completions.Count -gt 1) {
+        $completions = @($completions | Where-Object { -not $deprecated.Contains("$(_king_key $_)") })
+    }
+    $completions | ForEach-Object {
+        [System.Management.Automation.CompletionResult]::new((_king_quote ($prefix + $_.CompletionText)), $_.ListItemText, $_.ResultType, $_.ToolTip)
+    }
+}
@@ -0,0 +1,222 @@
+package king
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+)
+
+// Validate checks the completion script for the shell, one of "bash", "zsh", "fish", "powershell" or "nushell".
+// It first checks that all quotes and command substitutions in the script are terminated, this is done in Go
+// and works everywhere. If the shell is installed it is then asked to parse the script, without executing it.
+func Validate(shell string, script []byte) error {
+	if err := balanced(shell, script); err != nil {
+		return err
+	}
+	var args []string
+	switch shell {
+	case "bash", "zsh":
+		args = []string{shell, "-n"}
+	case "fish":
+		args = []string{"fish", "--no-config", "--no-execute"}
+	case "powershell":
+		args = []string{"pwsh", "-NoProfile", "-NonInteractive", "-Command",
+			`$errs = $null; [void][System.Management.Automation.Language.Parser]::ParseFile($args[0], [ref]$null, [ref]$errs); if ($errs) { $errs; exit 1 }`}
+	case "nushell":
+		args = []string{"nu", "--no-config-file", "-c", `source $env.KING_SCRIPT`}
+	default:
+		return fmt.Errorf("king: unknown shell %q", shell)
+	}
+	if _, err := exec.LookPath(args[0]); err != nil {
+		return nil
+	}
+
+	f, err := os.CreateTemp("", "king-validate-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(f.Name())
+	if _, err := f.Write(script); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+
+	cmd := exec.Command(args[0], args[1:]...)
+	if shell == "nushell" {
+		cmd.Env = append(os.Environ(), "KING_SCRIPT="+f.Name())
+	} else {
+		cmd.Args = append(cmd.Args, f.Name())
+	}
+	if out, err := cmd.CombinedOutput(); err != nil {
+		return fmt.Errorf("king: %s: %s: %s", shell, err, out)
+	}
+	return nil
+}
+
+// quote is an opened quote or substitution as tracked by balanced.
+type quote struct {
+	kind  byte // ', ", `, $ (for $'...'), ( (for $(...)) or { (for ${...}).
+	line  int
+	depth int // Nesting of parentheses or braces in a substitution.
+}
+
+func (q quote) String() string {
+	switch q.kind {
+	case '\'':
+		return "single quote"
+	case '"':
+		return "double quote"
+	case '`':
+		return "backtick"
+	case '$':
+		return "$'...' quote"
+	case '(':
+		return "$(...) substitution"
+	}
+	return "${...} expansion"
+}
+
+// balanced checks that the quotes and substitutions in the script are terminated, using the quoting rules of
+// the shell.
+func balanced(shell string, script []byte) error {
+	posix := shell == "bash" || shell == "zsh"
+	escape := byte('\\')
+	if shell == "powershell" {
+		escape = '`'
+	}
+
+	stack := []quote{}
+	push := func(kind byte, line int) { stack = append(stack, quote{kind: kind, line: line}) }
+	line := 1
+	for i := 0; i < len(script); i++ {
+		c := script[i]
+		next := byte(0)
+		if i+1 < len(script) {
+			next = script[i+1]
+		}
+		if c == '\n' {
+			line++
+		}
+		// skip consumes the escaped character.
+		skip := func() {
+			if next == '\n' {
+				line++
+			}
+			i++
+		}
+
+		top := byte(0)
+		if len(stack) > 0 {
+			top = stack[len(stack)-1].kind
+		}
+		switch top {
+		case '\'':
+			switch {
+			case c == '\\' && shell == "fish":
+				skip()
+			case c == '\'' && next == '\'' && shell == "powershell":
+				i++
+			case c == '\'':
+				stack = stack[:len(stack)-1]
+			}
+			continue
+
+		case '$':
+			switch c {
+			case '\\':
+				skip()
+			case '\'':
+				stack = stack[:len(stack)-1]
+			}
+			continue
+
+		case '`':
+			switch {
+			case c == '\\' && posix:
+				skip()
+			case c == '`':
+				stack = stack[:len(stack)-1]
+			}
+			continue
+
+		case '"':
+			switch {
+			case c == escape && shell != "nushell" || c == '\\' && shell == "nushell":
+				skip()
+			case c == '"' && next == '"' && shell == "powershell":
+				i++
+			case c == '"':
+				stack = stack[:len(stack)-1]
+			case c == '$' && next == '(' && shell != "nushell":
+				push('(', line)
+				i++
+			case c == '$' && next == '{' && posix:
+				push('{', line)
+				i++
+			case c == '`' && posix:
+				push('`', line)
+			}
+			continue
+		}
+
+		// Code, either at the top level or in a substitution.
+		switch {
+		case c == escape:
+			skip()
+		case c == '#' && (i == 0 || isSpace(script[i-1])):
+			for i+1 < len(script) && script[i+1] != '\n' {
+				i++
+			}
+		case c == 'r' && next == '#' && shell == "nushell": // raw string: r#'...'#
+			hashes := 1
+			for i+1+hashes < len(script) && script[i+1+hashes] == '#' {
+				hashes++
+			}
+			if i+1+hashes >= len(script) || script[i+1+hashes] != '\'' {
+				continue
+			}
+			end := bytes.Index(script[i+2+hashes:], []byte("'"+strings.Repeat("#", hashes)))
+			if end < 0 {
+				return fmt.Errorf("king: %s: unterminated raw string on line %d", shell, line)
+			}
+			raw := script[i : i+2+hashes+end+1+hashes]
+			line += bytes.Count(raw, []byte("\n"))
+			i += len(raw) - 1
+		case c == '$' && next == '\'' && posix:
+			push('$', line)
+			i++
+		case c == '\'':
+			push('\'', line)
+		case c == '"':
+			push('"', line)
+		case c == '`' && (posix || shell == "nushell"):
+			push('`', line)
+		case c == '$' && next == '(' && (posix || shell == "powershell" || shell == "fish"):
+			push('(', line)
+			i++
+		case c == '$' && next == '{' && posix:
+			push('{', line)
+			i++
+		case top == '(' && c == '(', top == '{' && c == '{':
+			stack[len(stack)-1].depth++
+		case top == '(' && c == ')', top == '{' && c == '}':
+			if stack[len(stack)-1].depth == 0 {
+				stack = stack[:len(stack)-1]
+				continue
+			}
+			stack[len(stack)-1].depth--
+		}
+	}
+	if len(stack) > 0 {
+		q := stack[len(stack)-1]
+		return fmt.Errorf("king: %s: unterminated %s on line %d", shell, q, q.line)
+	}
+	return nil
+}
+
+func isSpace(c byte) bool { return c == ' ' || c == '\t' || c == '\n' }
@@ -0,0 +1,113 @@
+package king
+
+import (
+	"io"
+	"testing"
+
+	"github.com/alecthomas/kong"
+)
+
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		shell     string
+		completer Completer
+	}{
+		{"bash", &Bash{}},
+		{"bash", &Bash{Descriptions: true}},
+		{"bash", &Bash{Dynamic: true}},
+		{"zsh", &Zsh{}},
+		{"zsh", &Zsh{Dynamic: true}},
+		{"fish", &Fish{}},
+		{"fish", &Fish{Dynamic: true}},
+		{"powershell", &PowerShell{}},
+		{"powershell", &PowerShell{Dynamic: true}},
+		{"nushell", &Nushell{}},
+		{"nushell", &Nushell{Dynamic: true}},
+	}
+	for i, tc := range tests {
+		parser := kong.Must(&G{})
+		if err := tc.completer.Completion(parser.Model.Node, "g"); err != nil {
+			t.Fatal(err)
+		}
+		if err := Validate(tc.shell, tc.completer.Out()); err != nil {
+			t.Errorf("test %d, expected no error, got %s", i, err)
+		}
+	}
+}
+
+func TestBalanced(t *testing.T) {
+	tests := []struct {
+		shell  string
+		script string
+		err    string
+	}{
+		{"bash", `echo 'a"b' "c'd"`, ""},
+		{"bash", `echo "$(compgen -W "a b" -- "$cur")"`, ""},
+		{"bash", `echo $'it\'s' ${#words[@]} # don't`, ""},
+		{"bash", "case $w in\n  a) echo \"x\" ;;\nesac", ""},
+		{"bash", "echo \"a\necho b", "king: bash: unterminated double quote on line 1"},
+		{"bash", "echo ok\necho 'it's'", "king: bash: unterminated single quote on line 2"},
+		{"bash", `echo "$(echo "a)"`, "king: bash: unterminated $(...) substitution on line 1"},
+		{"zsh", `_arguments '(--json)'"--json[json output]" '*:file:_files'`, ""},
+		{"zsh", `_arguments "--json[a "quote]"`, "king: zsh: unterminated double quote on line 1"},
+		{"fish", `complete -c g -d 'it\'s'`, ""},
+		{"fish", `complete -c g -d 'it's'`, "king: fish: unterminated single quote on line 1"},
+		{"powershell", `_king_result 'it''s' "a` + "`" + `"b"`, ""},
+		{"powershell", `_king_result 'it's'`, "king: powershell: unterminated single quote on line 1"},
+		{"nushell", "  --status: string  # it's", ""},
+		{"nushell", `def "nu-complete g" [] { ['a' "b\"c"] }`, ""},
+		{"nushell", `def "nu-complete g [] { }`, "king: nushell: unterminated double quote on line 1"},
+		{"nushell", `[r#'it's'# 'a']`, ""},
+		{"nushell", "[r##'it'#s\n'#", "king: nushell: unterminated raw string on line 1"},
+	}
+	for i, tc := range tests {
+		err := balanced(tc.shell, []byte(tc.script))
+		got := ""
+		if err != nil {
+			got = err.Error()
+		}
+		if got != tc.err {
+			t.Errorf("test %d, expected error %q, got %q", i, tc.err, got)
+		}
+	}
+}
+
+func TestWriteValidate(t *testing.T) {
+	b := &Bash{Validate: true, name: "g", completion: []byte("echo 'a\n")}
+	if err := b.Write(io.Discard); err == nil {
+		t.Errorf("expected error for unterminated quote")
+	}
+}
+
+// H has help texts and enum values with all characters that have a meaning in one of the shells.
+type H struct {
+	Quote  string "enum:\"it's,a\\\"b,$HOME,a[1],a:b,a b,(x),`date`\" default:\"it's\" help:\"It's a \\\"quote\\\" with $HOME, [brackets], a: colon and `date`.\""
+	Dollar bool   `help:"Costs $(rm -rf /) and $HOME \\ back-slash."`
+
+	Cmd struct {
+		Arg string "arg:\"\" help:\"Arg's \\\"help\\\" [1]: `x`.\""
+	} "cmd:\"\" help:\"It's a [command]: with \\\"quotes\\\", $HOME and `date`.\""
+}
+
+func TestValidateHostile(t *testing.T) {
+	tests := []struct {
+		shell     string
+		completer Completer
+	}{
+		{"bash", &Bash{}},
+		{"bash", &Bash{Descriptions: true}},
+		{"zsh", &Zsh{}},
+		{"fish", &Fish{}},
+		{"powershell", &PowerShell{}},
+		{"nushell", &Nushell{}},
+	}
+	for i, tc := range tests {
+		parser := kong.Must(&H{})
+		if err := tc.completer.Completion(parser.Model.Node, "h"); err != nil {
+			t.Fatal(err)
+		}
+		if err := Validate(tc.shell, tc.completer.Out()); err != nil {
+			t.Errorf("test %d, expected no error, got %s", i, err)
+		}
+	}
+}
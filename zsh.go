@@ -14,6 +14,8 @@ type Zsh struct {
 	name       string
 	completion []byte
 	Flags      []*kong.Flag // Any global flags that the should Application Node have.
+	Dynamic    bool         // If true only a shim is generated that calls the [CompleteName] command of the application.
+	Validate   bool         // If true Write checks the completion with [Validate] first.
 }
 
 func (z *Zsh) Out() []byte { return z.completion }
@@ -22,15 +24,22 @@ func (z *Zsh) Write(w ...io.Writer) error {
 	if z.completion == nil {
 		return fmt.Errorf("no completion")
 	}
+	if z.Validate {
+		if err := Validate("zsh", z.completion); err != nil {
+			return err
+		}
+	}
 	if len(w) > 0 {
 		w[0].Write(z.completion)
 	}
 	return os.WriteFile("_"+z.name, z.completion, 0644)
 }
 
-func (z *Zsh) Completion(k *kong.Node, altname string) {
-	k.Flags = append(k.Flags, z.Flags...)
-
+func (z *Zsh) Completion(k *kong.Node, altname string) error {
+	if err := check(k, z.Flags); err != nil {
+		return err
+	}
+	addFlags(k, z.Flags)
 	format := `#compdef %[1]s
 compdef _%[1]s %[1]s
 # zsh completion for %[1]s
@@ -45,23 +54,68 @@ compdef _%[1]s %[1]s
 		k.Name = altname
 	}
 	fmt.Fprintf(&out, format, z.name)
-	z.gen(&out, k)
+	if z.Dynamic {
+		z.shim(&out, k)
+	} else {
+		z.gen(&out, k)
+	}
 	z.completion = []byte(out.String())
+	return nil
+}
+
+// zshQuote returns s escaped for use in a double quoted zsh string.
+func zshQuote(s string) string {
+	return strings.NewReplacer(`\`, `\\`, `"`, `\"`, "$", `\$`, "`", "\\`").Replace(s)
 }
 
-func (z Zsh) writeFlag(buf io.StringWriter, f *kong.Flag) {
+// zshSpec returns s escaped for use in a spec of _arguments or _values, that is part of a double quoted string.
+// The characters in special, and backslashes, are escaped with a backslash, as these have a meaning in the spec.
+func zshSpec(s, special string) string {
+	e := &strings.Builder{}
+	for _, r := range s {
+		if r == '\\' || strings.ContainsRune(special, r) {
+			e.WriteRune('\\')
+		}
+		e.WriteRune(r)
+	}
+	return zshQuote(e.String())
+}
+
+// zshWord returns the literal word w escaped for an action list, "(a b c)", in a spec. The list is evaluated by
+// _arguments, so all characters that have a meaning to the shell are escaped.
+func zshWord(w string) string {
+	e := &strings.Builder{}
+	for _, r := range w {
+		if !safe(r) || r == ':' {
+			e.WriteRune('\\')
+		}
+		e.WriteRune(r)
+	}
+	return zshQuote(e.String())
+}
+
+// writeFlag writes the _arguments spec for f. The exclusion list holds the names of the flags that are no longer
+// offered once f is given, see [exclusive]. Repeatable flags get a '*' so they are offered again. For map flags
+// the completion completes the keys, with a '=' appended.
+func (z Zsh) writeFlag(buf io.StringWriter, f *kong.Flag, excl []string) {
 	var str strings.Builder
 	str.WriteString("        ")
+	spec := ""
+	if len(excl) > 0 {
+		spec = "(" + strings.Join(excl, " ") + ")"
+	}
+	if repeatable(f.Value) {
+		spec += "*"
+	}
+	exclusion := ""
+	if spec != "" {
+		exclusion = "'" + spec + "'"
+	}
+	str.WriteString(exclusion)
 	if f.Short != 0 {
-		str.WriteString("'(")
-		str.WriteString(fmt.Sprintf("-%c --%s", f.Short, f.Name))
-		if !f.IsBool() {
-			str.WriteString("=")
-		}
-		str.WriteString(")'")
 		str.WriteString("{")
 		str.WriteString(fmt.Sprintf("-%c,--%s", f.Short, f.Name))
-		if !f.IsBool() {
+		if takesValue(f) {
 			str.WriteString("=")
 		}
 		str.WriteString("}")
@@ -69,21 +123,21 @@ func (z Zsh) writeFlag(buf io.StringWriter, f *kong.Flag) {
 	} else {
 		str.WriteString("\"")
 		str.WriteString(fmt.Sprintf("--%s", f.Name))
-		if !f.IsBool() {
+		if takesValue(f) {
 			str.WriteString("=")
 		}
 	}
-	str.WriteString(fmt.Sprintf("[%s]", f.Help))
-	if !f.IsBool() {
+	str.WriteString(fmt.Sprintf("[%s]", zshSpec(flagHelp(f), "[]")))
+	if takesValue(f) {
 		str.WriteString(":")
-		str.WriteString(strings.ToLower(f.Help))
+		str.WriteString(zshSpec(strings.ToLower(plain(f.Help)), ":"))
 		str.WriteString(":")
 	}
 	values := flagEnums(f)
 	if len(values) > 0 {
 		str.WriteString("(")
-		for i, v := range f.EnumSlice() {
-			str.WriteString(v)
+		for i, v := range values {
+			str.WriteString(zshWord(v))
 			if i < len(values)-1 {
 				str.WriteString(" ")
 			}
@@ -103,13 +157,12 @@ func (z Zsh) writeFlag(buf io.StringWriter, f *kong.Flag) {
 	}
 	comptag := completion(f.Value, "zsh")
 	if comptag != "" {
-		if f.IsBool() {
-			panic("king: a boolean flag can not have completion")
-		}
-
-		if strings.HasPrefix(comptag, "_") { // action
+		switch {
+		case strings.HasPrefix(comptag, "_"): // action
 			str.WriteString(comptag)
-		} else {
+		case isMap(f.Value):
+			str.WriteString(fmt.Sprintf(`compadd -S = -- %s`, comptag))
+		default:
 			str.WriteString(fmt.Sprintf(`_values '%s' %s`, f.Name, comptag))
 		}
 	}
@@ -118,9 +171,9 @@ func (z Zsh) writeFlag(buf io.StringWriter, f *kong.Flag) {
 
 	if f.Tag.Negatable != "" {
 		// implied boolean
-		str.WriteString(" \\\n        \"")
-		str.WriteString(fmt.Sprintf("--no-%s", f.Name))
-		str.WriteString(fmt.Sprintf("[%s]", f.Help))
+		str.WriteString(" \\\n        " + exclusion + "\"")
+		str.WriteString(negation(f))
+		str.WriteString(fmt.Sprintf("[%s]", zshSpec(flagHelp(f), "[]")))
 		str.WriteString("\"")
 
 	}
@@ -132,7 +185,11 @@ func (z Zsh) writeFlags(buf io.StringWriter, cmd *kong.Node) {
 		if f.Hidden {
 			continue
 		}
-		z.writeFlag(buf, f)
+		excl := []string{}
+		for _, x := range exclusive(f, cmd.Flags) {
+			excl = append(excl, flagNames(x)...)
+		}
+		z.writeFlag(buf, f, excl)
 		if i < len(cmd.Flags)-1 {
 			writeString(buf, " \\\n")
 		}
@@ -142,11 +199,20 @@ func (z Zsh) writeFlags(buf io.StringWriter, cmd *kong.Node) {
 func (z Zsh) writePositional(buf io.StringWriter, cmd *kong.Node) {
 	// '1: : _values "<name>" $(c volume-server list --comp)'  -- when there is completion
 	// '2:yubikey:' -- when there is no completion, this is the name of the node.
+	// '3::yubikey:' -- when the argument is optional.
+	// '*:yubikey:' -- when the argument is variadic, this takes all remaining arguments.
 	for i, p := range cmd.Positional {
+		spec := fmt.Sprintf("%d:", i+1)
+		switch {
+		case variadic(p):
+			spec = "*:"
+		case !p.Required:
+			spec += ":"
+		}
 		if comptag := completion(p, "zsh"); comptag == "" {
-			writeString(buf, fmt.Sprintf("        \"%d:%s:\"", i+1, strings.ToLower(p.Name)))
+			writeString(buf, fmt.Sprintf("        \"%s%s:\"", spec, strings.ToLower(p.Name)))
 		} else {
-			writeString(buf, fmt.Sprintf("        '%d: : _values \"%s\" %s'", i+1, p.Name, comptag))
+			writeString(buf, fmt.Sprintf("        '%s : _values \"%s\" %s'", spec, p.Name, comptag))
 		}
 		if i < len(cmd.Positional)-1 {
 			writeString(buf, " \\\n")
@@ -155,7 +221,7 @@ func (z Zsh) writePositional(buf io.StringWriter, cmd *kong.Node) {
 }
 
 func (z Zsh) writeCommand(buf io.StringWriter, c *kong.Node) {
-	writeString(buf, fmt.Sprintf("                \"%s[%s]\"", c.Name, c.Help))
+	writeString(buf, fmt.Sprintf("                \"%s[%s]\"", zshSpec(c.Name, ":[]"), zshSpec(c.Help, "[]")))
 }
 
 func (z Zsh) writeCommands(buf io.StringWriter, cmd *kong.Node) {
@@ -163,11 +229,12 @@ func (z Zsh) writeCommands(buf io.StringWriter, cmd *kong.Node) {
 		if c == nil || c.Hidden {
 			continue
 		}
+		help := commandHelp(c)
 		for _, a := range c.Aliases {
-			z.writeCommand(buf, &kong.Node{Name: a, Help: c.Help})
+			z.writeCommand(buf, &kong.Node{Name: a, Help: help})
 			buf.WriteString(" \\\n")
 		}
-		z.writeCommand(buf, c)
+		z.writeCommand(buf, &kong.Node{Name: c.Name, Help: help})
 		if i < len(cmd.Children)-1 {
 			buf.WriteString(" \\")
 		}
@@ -228,3 +295,31 @@ func (z Zsh) gen(buf io.StringWriter, cmd *kong.Node) {
 	writeString(buf, "\n")
 	writeString(buf, "}\n\n")
 }
+
+// shim writes a completion function that calls the application to get the completions.
+func (z Zsh) shim(buf io.StringWriter, cmd *kong.Node) {
+	format := `_%[1]s() {
+    local -a completions keys
+    local line action
+
+    for line in "${(@f)$(%[1]s %[2]s -- "${(@)words[2,$CURRENT]}" 2>/dev/null)}"; do
+        case "$line" in
+            :*) action=${line#:} ;;
+            *=$'\t'*) keys+=("${${line%%%%$'\t'*}//:/\\:}:${line#*$'\t'}") ;;
+            ?*) completions+=("${${line%%%%$'\t'*}//:/\\:}:${line#*$'\t'}") ;;
+        esac
+    done
+
+    (( ${#completions} )) && _describe -t values '%[1]s' completions
+    (( ${#keys} )) && _describe -t keys '%[1]s' keys -S ''
+    case "$action" in
+        file) _files ;;
+        directory) _files -/ ;;
+        group) _groups ;;
+        user) _users ;;
+        export) _parameters ;;
+    esac
+}
+`
+	writeString(buf, fmt.Sprintf(format, z.name, CompleteName))
+}
@@ -2,56 +2,49 @@ package king
 
 import (
 	"bytes"
-	"html/template"
-	"os"
 	"os/exec"
+	"strings"
 	"testing"
 
 	"github.com/alecthomas/kong"
+	"github.com/miekg/king/internal/comptest"
 )
 
-func compzTest(t *testing.T, completionfile, exe string) []byte {
-	tmpl, err := template.New("comptest.zsh.tmpl").ParseFiles("testdata/comptest.zsh.tmpl")
-	if err != nil {
-		panic(err)
-	}
-	c := CompTest{Compfile: completionfile, Comptest: exe}
-
-	f, err := os.Create("comptest.zsh")
-	err = tmpl.Execute(f, c)
-	if err != nil {
-		t.Fatal(err)
+// compzTest returns the completions zsh gives for line with the completion script, the completions are joined
+// with a space.
+func compzTest(t *testing.T, script []byte, line string) string {
+	t.Helper()
+	if _, err := exec.LookPath("zsh"); err != nil {
+		t.Skip("zsh not found")
 	}
-	defer os.Remove("comptest.zsh")
-	os.Chmod("comptest.zsh", 0o755)
-	cmd := exec.Command("zsh", "./comptest.zsh")
-	out, err := cmd.Output()
+	completions, err := comptest.Zsh(script, line)
 	if err != nil {
 		t.Fatal(err)
 	}
-	return bytes.TrimSpace(out)
+	return strings.Join(completions, " ")
 }
 
 func TestZsh(t *testing.T) {
 	parser := kong.Must(&T{})
 	manf := &kong.Flag{Value: &kong.Value{Name: "man", Help: "how context-sensitive manual page.", Tag: &kong.Tag{}}}
 	z := &Zsh{Flags: []*kong.Flag{manf}}
-	z.Completion(parser.Model.Node, "myexe")
-	z.Write()
+	if err := z.Completion(parser.Model.Node, "myexe"); err != nil {
+		t.Fatal(err)
+	}
 
 	tests := []struct {
 		exe    string
 		expect string
 	}{
-		{"myexe --", "--help\r\n--man"},
+		{"myexe --", "--help --man"},
 		{"myexe --m", "--man"},
-		{"myexe ", "d\r\ndo\r\nagain\r\nmore\r\neven-more"},
+		{"myexe ", "d do again more even-more"},
 	}
 
 	for i := range tests {
-		out := compzTest(t, "_myexe", tests[i].exe)
-		if string(out) != tests[i].expect {
-			t.Errorf("test %d, expected %q, got %q", i, tests[i].expect, string(out))
+		out := compzTest(t, z.Out(), tests[i].exe)
+		if out != tests[i].expect {
+			t.Errorf("test %d, expected %q, got %q", i, tests[i].expect, out)
 		}
 	}
 }
@@ -60,9 +53,96 @@ func TestAction(t *testing.T) {
 	parser := kong.Must(&T1{})
 	z := &Zsh{}
 	const exp = `--super-string=[complete this string]:complete this string:_values 'super-string' $(echo bla bloep)"`
-	z.Completion(parser.Model.Node, "t1")
+	if err := z.Completion(parser.Model.Node, "t1"); err != nil {
+		t.Fatal(err)
+	}
 	ok := bytes.Contains(z.Out(), []byte(exp))
 	if !ok {
 		t.Fatalf("expected %s to be present, but did not found it", exp)
 	}
 }
+
+func TestZshExclusive(t *testing.T) {
+	parser := kong.Must(&X{})
+	z := &Zsh{}
+	if err := z.Completion(parser.Model.Node, "x"); err != nil {
+		t.Fatal(err)
+	}
+	for _, expect := range []string{
+		`'(--json --yaml --no-yaml)'"--json[output json.]"`,
+		`'(--yaml --no-yaml --json)'"--no-yaml[output yaml.]"`,
+		`'(--status -s)'{-s,--status=}"[set the status.]`,
+	} {
+		if !bytes.Contains(z.Out(), []byte(expect)) {
+			t.Errorf("expected %s in completion, got\n%s", expect, z.Out())
+		}
+	}
+}
+
+func TestZshHostile(t *testing.T) {
+	parser := kong.Must(&H{})
+	z := &Zsh{}
+	if err := z.Completion(parser.Model.Node, "h"); err != nil {
+		t.Fatal(err)
+	}
+	for _, expect := range []string{
+		`"--quote=[It's a \"quote\" with \$HOME, \\[brackets\\], a: colon and date.]`,
+		`:(it\\'s a\\\"b \\\$HOME a\\[1\\] a\\:b a\\ b \\(x\\) \\\` + "`" + `date\\\` + "`" + `)"`,
+		`"--dollar[Costs \$(rm -rf /) and \$HOME \\\\ back-slash.]"`,
+		`"cmd[It's a \\[command\\]: with \"quotes\", \$HOME and date.]"`,
+	} {
+		if !bytes.Contains(z.Out(), []byte(expect)) {
+			t.Errorf("expected %s in completion, got\n%s", expect, z.Out())
+		}
+	}
+	if out := compzTest(t, z.Out(), "h --quote $H"); out != "$HOME" {
+		t.Errorf("expected %q, got %q", "$HOME", out)
+	}
+}
+
+func TestZshDeprecated(t *testing.T) {
+	parser := kong.Must(&D{})
+	z := &Zsh{}
+	if err := z.Completion(parser.Model.Node, "d"); err != nil {
+		t.Fatal(err)
+	}
+	expect := `"--json[(deprecated: use --format=json) output json.]"`
+	if !bytes.Contains(z.Out(), []byte(expect)) {
+		t.Errorf("expected %s in completion, got\n%s", expect, z.Out())
+	}
+	if out := compzTest(t, z.Out(), "d --j"); out != "--json" {
+		t.Errorf("expected %q, got %q", "--json", out)
+	}
+}
+
+func TestZshRepeatable(t *testing.T) {
+	parser := kong.Must(&R{})
+	z := &Zsh{}
+	if err := z.Completion(parser.Model.Node, "r"); err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		line   string
+		expect string
+	}{
+		{"r -v --verb", "--verbose"},
+		{"r --tag a --ta", "--tag"},
+		{"r --status ok --st", ""},
+	}
+	for _, tc := range tests {
+		if got := compzTest(t, z.Out(), tc.line); got != tc.expect {
+			t.Errorf("for %q, expected %q, got %q", tc.line, tc.expect, got)
+		}
+	}
+}
+
+func TestZshVariadic(t *testing.T) {
+	parser := kong.Must(&V{})
+	z := &Zsh{}
+	if err := z.Completion(parser.Model.Node, "v"); err != nil {
+		t.Fatal(err)
+	}
+	if out := compzTest(t, z.Out(), "v rm s1 a "); out != "a b" {
+		t.Errorf("expected %q, got %q", "a b", out)
+	}
+}